@@ -1,37 +1,82 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/mkvy/movies-app/internal/kafkatrace"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"github.com/mkvy/movies-app/pkg/tracing"
 	"github.com/mkvy/movies-app/rating/pkg/model"
-	"log"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
 	"os"
+	"strings"
 	"time"
 )
 
+const serviceName = "ratingingester"
+
 func main() {
-	log.Println("Creating a kafka producer")
+	addr := flag.String("addr", "localhost:9092", "Kafka bootstrap servers")
+	topic := flag.String("topic", "ratings", "Kafka topic to produce rating events to")
+	fileName := flag.String("file", "./cmd/ratingingester/ratingsdata.json", "JSON file with rating events")
+	timeout := flag.Duration("flush-timeout", 10*time.Second, "time to wait until all events get produced")
+	tracingCfg := tracing.Config{Insecure: true, Sampler: tracing.SamplerParentBasedAlwaysOn}
+	flag.StringVar(&tracingCfg.Exporter, "tracing-exporter", tracing.ExporterOTLPGRPC, "trace exporter: otlp-grpc, otlp-http, stdout, file or none")
+	flag.StringVar(&tracingCfg.Endpoint, "tracing-endpoint", "localhost:4317", "trace collector host:port")
+	flag.StringVar(&tracingCfg.Path, "tracing-file", "traces.jsonl", "file of the file trace exporter")
+	propagators := flag.String("tracing-propagators", "tracecontext,baggage", "comma-separated trace context formats injected into event headers")
+	flag.Parse()
 
-	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:9092"})
+	logger, _, err := logging.New(logging.Config{Level: "info", Format: logging.FormatConsole}, serviceName)
 	if err != nil {
 		panic(err)
 	}
+	defer logger.Sync()
+
+	ctx := context.Background()
+	tracingCfg.Propagators = strings.Split(*propagators, ",")
+	if err := tracingCfg.Validate(); err != nil {
+		logger.Fatal("Invalid tracing configuration", zap.Error(err))
+	}
+	tp, err := tracing.NewProvider(ctx, tracingCfg, serviceName, discovery.GenerateInstanceID(serviceName))
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.Warn("Failed to flush traces", zap.Error(err))
+		}
+	}()
+	propagator, err := tracing.NewPropagator(tracingCfg.Propagators)
+	if err != nil {
+		logger.Fatal("Failed to initialize trace propagation", zap.Error(err))
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+
+	logger.Info("Creating a kafka producer", zap.String("addr", *addr))
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": *addr})
+	if err != nil {
+		logger.Fatal("Failed to create a kafka producer", zap.Error(err))
+	}
 	defer producer.Close()
 
-	const fileName = "./cmd/ratingingester/ratingsdata.json"
-	log.Println("Reading rating events from file " + fileName)
+	logger.Info("Reading rating events", zap.String("file", *fileName))
 
-	ratingEvents, err := readRatingEvents(fileName)
+	ratingEvents, err := readRatingEvents(*fileName)
 	if err != nil {
-		panic(err)
+		logger.Fatal("Failed to read rating events", zap.Error(err))
 	}
 
-	var topic = "ratings"
-	if err := produceRatingEvents(topic, producer, ratingEvents); err != nil {
-		panic(err)
+	if err := produceRatingEvents(ctx, *topic, producer, ratingEvents); err != nil {
+		logger.Fatal("Failed to produce rating events", zap.Error(err))
 	}
-	timeout := 10 * time.Second
-	log.Println("Waiting " + timeout.String() + " until all events get produced")
+	logger.Info("Waiting until all events get produced", zap.Duration("timeout", *timeout))
 	producer.Flush(int(timeout.Milliseconds()))
 }
 
@@ -48,17 +93,23 @@ func readRatingEvents(fileName string) ([]model.RatingEvent, error) {
 	return ratings, nil
 }
 
-func produceRatingEvents(topic string, producer *kafka.Producer, events []model.RatingEvent) error {
+// produceRatingEvents produces events with the context of a producer span in message headers,
+// so that their processing by the rating service is part of the same trace.
+func produceRatingEvents(ctx context.Context, topic string, producer *kafka.Producer, events []model.RatingEvent) error {
 	for _, event := range events {
 		encodedEvent, err := json.Marshal(event)
 		if err != nil {
 			return err
 		}
 
-		if err := producer.Produce(&kafka.Message{
+		msg := &kafka.Message{
 			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 			Value:          encodedEvent,
-		}, nil); err != nil {
+		}
+		_, span := kafkatrace.StartProducerSpan(ctx, msg)
+		err = producer.Produce(msg, nil)
+		span.End()
+		if err != nil {
 			return err
 		}
 	}
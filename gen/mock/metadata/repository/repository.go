@@ -56,3 +56,16 @@ func (m_2 *MockmetadataRepository) Put(ctx context.Context, id string, m *model.
 func (mr *MockmetadataRepositoryMockRecorder) Put(ctx, id, m interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockmetadataRepository)(nil).Put), ctx, id, m)
 }
+
+// Search mocks base method.
+func (m *MockmetadataRepository) Search(ctx context.Context, query string, limit int) ([]*model.Metadata, error) {
+	ret := m.ctrl.Call(m, "Search", ctx, query, limit)
+	ret0, _ := ret[0].([]*model.Metadata)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockmetadataRepositoryMockRecorder) Search(ctx, query, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockmetadataRepository)(nil).Search), ctx, query, limit)
+}
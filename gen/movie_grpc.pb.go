@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MovieService_GetMovieDetails_FullMethodName = "/MovieService/GetMovieDetails"
+	MovieService_GetMovieDetails_FullMethodName   = "/MovieService/GetMovieDetails"
+	MovieService_WatchMovieDetails_FullMethodName = "/MovieService/WatchMovieDetails"
 )
 
 // MovieServiceClient is the client API for MovieService service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MovieServiceClient interface {
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
+	WatchMovieDetails(ctx context.Context, in *WatchMovieDetailsRequest, opts ...grpc.CallOption) (MovieService_WatchMovieDetailsClient, error)
 }
 
 type movieServiceClient struct {
@@ -46,11 +48,44 @@ func (c *movieServiceClient) GetMovieDetails(ctx context.Context, in *GetMovieDe
 	return out, nil
 }
 
+func (c *movieServiceClient) WatchMovieDetails(ctx context.Context, in *WatchMovieDetailsRequest, opts ...grpc.CallOption) (MovieService_WatchMovieDetailsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MovieService_ServiceDesc.Streams[0], MovieService_WatchMovieDetails_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &movieServiceWatchMovieDetailsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MovieService_WatchMovieDetailsClient interface {
+	Recv() (*WatchMovieDetailsResponse, error)
+	grpc.ClientStream
+}
+
+type movieServiceWatchMovieDetailsClient struct {
+	grpc.ClientStream
+}
+
+func (x *movieServiceWatchMovieDetailsClient) Recv() (*WatchMovieDetailsResponse, error) {
+	m := new(WatchMovieDetailsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MovieServiceServer is the server API for MovieService service.
 // All implementations must embed UnimplementedMovieServiceServer
 // for forward compatibility
 type MovieServiceServer interface {
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
+	WatchMovieDetails(*WatchMovieDetailsRequest, MovieService_WatchMovieDetailsServer) error
 	mustEmbedUnimplementedMovieServiceServer()
 }
 
@@ -61,6 +96,9 @@ type UnimplementedMovieServiceServer struct {
 func (UnimplementedMovieServiceServer) GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMovieDetails not implemented")
 }
+func (UnimplementedMovieServiceServer) WatchMovieDetails(*WatchMovieDetailsRequest, MovieService_WatchMovieDetailsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMovieDetails not implemented")
+}
 func (UnimplementedMovieServiceServer) mustEmbedUnimplementedMovieServiceServer() {}
 
 // UnsafeMovieServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +130,27 @@ func _MovieService_GetMovieDetails_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieService_WatchMovieDetails_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMovieDetailsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieServiceServer).WatchMovieDetails(m, &movieServiceWatchMovieDetailsServer{stream})
+}
+
+type MovieService_WatchMovieDetailsServer interface {
+	Send(*WatchMovieDetailsResponse) error
+	grpc.ServerStream
+}
+
+type movieServiceWatchMovieDetailsServer struct {
+	grpc.ServerStream
+}
+
+func (x *movieServiceWatchMovieDetailsServer) Send(m *WatchMovieDetailsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // MovieService_ServiceDesc is the grpc.ServiceDesc for MovieService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -104,13 +163,22 @@ var MovieService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _MovieService_GetMovieDetails_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMovieDetails",
+			Handler:       _MovieService_WatchMovieDetails_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "movie.proto",
 }
 
 const (
-	MetadataService_GetMetadata_FullMethodName = "/MetadataService/GetMetadata"
-	MetadataService_PutMetadata_FullMethodName = "/MetadataService/PutMetadata"
+	MetadataService_GetMetadata_FullMethodName      = "/MetadataService/GetMetadata"
+	MetadataService_PutMetadata_FullMethodName      = "/MetadataService/PutMetadata"
+	MetadataService_BatchGetMetadata_FullMethodName = "/MetadataService/BatchGetMetadata"
+	MetadataService_SearchMetadata_FullMethodName   = "/MetadataService/SearchMetadata"
+	MetadataService_WatchMetadata_FullMethodName    = "/MetadataService/WatchMetadata"
 )
 
 // MetadataServiceClient is the client API for MetadataService service.
@@ -119,6 +187,9 @@ const (
 type MetadataServiceClient interface {
 	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
 	PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error)
+	BatchGetMetadata(ctx context.Context, in *BatchGetMetadataRequest, opts ...grpc.CallOption) (*BatchGetMetadataResponse, error)
+	SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error)
+	WatchMetadata(ctx context.Context, in *WatchMetadataRequest, opts ...grpc.CallOption) (MetadataService_WatchMetadataClient, error)
 }
 
 type metadataServiceClient struct {
@@ -147,12 +218,65 @@ func (c *metadataServiceClient) PutMetadata(ctx context.Context, in *PutMetadata
 	return out, nil
 }
 
+func (c *metadataServiceClient) BatchGetMetadata(ctx context.Context, in *BatchGetMetadataRequest, opts ...grpc.CallOption) (*BatchGetMetadataResponse, error) {
+	out := new(BatchGetMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_BatchGetMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error) {
+	out := new(SearchMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_SearchMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) WatchMetadata(ctx context.Context, in *WatchMetadataRequest, opts ...grpc.CallOption) (MetadataService_WatchMetadataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MetadataService_ServiceDesc.Streams[0], MetadataService_WatchMetadata_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metadataServiceWatchMetadataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MetadataService_WatchMetadataClient interface {
+	Recv() (*WatchMetadataResponse, error)
+	grpc.ClientStream
+}
+
+type metadataServiceWatchMetadataClient struct {
+	grpc.ClientStream
+}
+
+func (x *metadataServiceWatchMetadataClient) Recv() (*WatchMetadataResponse, error) {
+	m := new(WatchMetadataResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MetadataServiceServer is the server API for MetadataService service.
 // All implementations must embed UnimplementedMetadataServiceServer
 // for forward compatibility
 type MetadataServiceServer interface {
 	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
 	PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error)
+	BatchGetMetadata(context.Context, *BatchGetMetadataRequest) (*BatchGetMetadataResponse, error)
+	SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error)
+	WatchMetadata(*WatchMetadataRequest, MetadataService_WatchMetadataServer) error
 	mustEmbedUnimplementedMetadataServiceServer()
 }
 
@@ -166,6 +290,15 @@ func (UnimplementedMetadataServiceServer) GetMetadata(context.Context, *GetMetad
 func (UnimplementedMetadataServiceServer) PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutMetadata not implemented")
 }
+func (UnimplementedMetadataServiceServer) BatchGetMetadata(context.Context, *BatchGetMetadataRequest) (*BatchGetMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) WatchMetadata(*WatchMetadataRequest, MetadataService_WatchMetadataServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMetadata not implemented")
+}
 func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
 
 // UnsafeMetadataServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -215,6 +348,63 @@ func _MetadataService_PutMetadata_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataService_BatchGetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).BatchGetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_BatchGetMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).BatchGetMetadata(ctx, req.(*BatchGetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_SearchMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_SearchMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, req.(*SearchMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_WatchMetadata_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMetadataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetadataServiceServer).WatchMetadata(m, &metadataServiceWatchMetadataServer{stream})
+}
+
+type MetadataService_WatchMetadataServer interface {
+	Send(*WatchMetadataResponse) error
+	grpc.ServerStream
+}
+
+type metadataServiceWatchMetadataServer struct {
+	grpc.ServerStream
+}
+
+func (x *metadataServiceWatchMetadataServer) Send(m *WatchMetadataResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,14 +420,30 @@ var MetadataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutMetadata",
 			Handler:    _MetadataService_PutMetadata_Handler,
 		},
+		{
+			MethodName: "BatchGetMetadata",
+			Handler:    _MetadataService_BatchGetMetadata_Handler,
+		},
+		{
+			MethodName: "SearchMetadata",
+			Handler:    _MetadataService_SearchMetadata_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMetadata",
+			Handler:       _MetadataService_WatchMetadata_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
 
 const (
-	RatingService_GetAggregatedRating_FullMethodName = "/RatingService/GetAggregatedRating"
-	RatingService_PutRating_FullMethodName           = "/RatingService/PutRating"
+	RatingService_GetAggregatedRating_FullMethodName       = "/RatingService/GetAggregatedRating"
+	RatingService_PutRating_FullMethodName                 = "/RatingService/PutRating"
+	RatingService_BatchGetAggregatedRatings_FullMethodName = "/RatingService/BatchGetAggregatedRatings"
+	RatingService_WatchAggregatedRating_FullMethodName     = "/RatingService/WatchAggregatedRating"
 )
 
 // RatingServiceClient is the client API for RatingService service.
@@ -246,6 +452,8 @@ const (
 type RatingServiceClient interface {
 	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
 	PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error)
+	BatchGetAggregatedRatings(ctx context.Context, in *BatchGetAggregatedRatingsRequest, opts ...grpc.CallOption) (*BatchGetAggregatedRatingsResponse, error)
+	WatchAggregatedRating(ctx context.Context, in *WatchAggregatedRatingRequest, opts ...grpc.CallOption) (RatingService_WatchAggregatedRatingClient, error)
 }
 
 type ratingServiceClient struct {
@@ -274,12 +482,55 @@ func (c *ratingServiceClient) PutRating(ctx context.Context, in *PutRatingReques
 	return out, nil
 }
 
+func (c *ratingServiceClient) BatchGetAggregatedRatings(ctx context.Context, in *BatchGetAggregatedRatingsRequest, opts ...grpc.CallOption) (*BatchGetAggregatedRatingsResponse, error) {
+	out := new(BatchGetAggregatedRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_BatchGetAggregatedRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) WatchAggregatedRating(ctx context.Context, in *WatchAggregatedRatingRequest, opts ...grpc.CallOption) (RatingService_WatchAggregatedRatingClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RatingService_ServiceDesc.Streams[0], RatingService_WatchAggregatedRating_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ratingServiceWatchAggregatedRatingClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RatingService_WatchAggregatedRatingClient interface {
+	Recv() (*WatchAggregatedRatingResponse, error)
+	grpc.ClientStream
+}
+
+type ratingServiceWatchAggregatedRatingClient struct {
+	grpc.ClientStream
+}
+
+func (x *ratingServiceWatchAggregatedRatingClient) Recv() (*WatchAggregatedRatingResponse, error) {
+	m := new(WatchAggregatedRatingResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RatingServiceServer is the server API for RatingService service.
 // All implementations must embed UnimplementedRatingServiceServer
 // for forward compatibility
 type RatingServiceServer interface {
 	GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error)
 	PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error)
+	BatchGetAggregatedRatings(context.Context, *BatchGetAggregatedRatingsRequest) (*BatchGetAggregatedRatingsResponse, error)
+	WatchAggregatedRating(*WatchAggregatedRatingRequest, RatingService_WatchAggregatedRatingServer) error
 	mustEmbedUnimplementedRatingServiceServer()
 }
 
@@ -293,6 +544,12 @@ func (UnimplementedRatingServiceServer) GetAggregatedRating(context.Context, *Ge
 func (UnimplementedRatingServiceServer) PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutRating not implemented")
 }
+func (UnimplementedRatingServiceServer) BatchGetAggregatedRatings(context.Context, *BatchGetAggregatedRatingsRequest) (*BatchGetAggregatedRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetAggregatedRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) WatchAggregatedRating(*WatchAggregatedRatingRequest, RatingService_WatchAggregatedRatingServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAggregatedRating not implemented")
+}
 func (UnimplementedRatingServiceServer) mustEmbedUnimplementedRatingServiceServer() {}
 
 // UnsafeRatingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -342,6 +599,45 @@ func _RatingService_PutRating_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatingService_BatchGetAggregatedRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetAggregatedRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).BatchGetAggregatedRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_BatchGetAggregatedRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).BatchGetAggregatedRatings(ctx, req.(*BatchGetAggregatedRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_WatchAggregatedRating_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAggregatedRatingRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RatingServiceServer).WatchAggregatedRating(m, &ratingServiceWatchAggregatedRatingServer{stream})
+}
+
+type RatingService_WatchAggregatedRatingServer interface {
+	Send(*WatchAggregatedRatingResponse) error
+	grpc.ServerStream
+}
+
+type ratingServiceWatchAggregatedRatingServer struct {
+	grpc.ServerStream
+}
+
+func (x *ratingServiceWatchAggregatedRatingServer) Send(m *WatchAggregatedRatingResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // RatingService_ServiceDesc is the grpc.ServiceDesc for RatingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -357,7 +653,17 @@ var RatingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutRating",
 			Handler:    _RatingService_PutRating_Handler,
 		},
+		{
+			MethodName: "BatchGetAggregatedRatings",
+			Handler:    _RatingService_BatchGetAggregatedRatings_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchAggregatedRating",
+			Handler:       _RatingService_WatchAggregatedRating_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
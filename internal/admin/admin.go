@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version and Commit identify the build, they are set with
+// -ldflags "-X github.com/mkvy/movies-app/internal/admin.Version=1.0.0 -X ...admin.Commit=<sha>".
+// Commit defaults to the VCS revision recorded by the Go toolchain.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Paths of built-in endpoints.
+const (
+	BuildInfoPath = "/buildinfo"
+	LogLevelPath  = "/loglevel"
+	PprofPath     = "/debug/pprof/"
+	VarsPath      = "/debug/vars"
+)
+
+// BuildInfo defines the build of the running binary.
+type BuildInfo struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit,omitempty"`
+	Modified  bool      `json:"modified,omitempty"`
+	GoVersion string    `json:"goVersion"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// Server defines a handler of administrative and debugging endpoints: pprof, expvar, build info,
+// a log level toggle and JSON snapshots of runtime state published by components.
+// It performs no authentication and must only be served on a private listener.
+type Server struct {
+	mux   *http.ServeMux
+	build BuildInfo
+
+	mu        sync.RWMutex
+	published map[string]func() any
+}
+
+// New creates a new admin server. The log level is changed with GET and PUT requests
+// to LogLevelPath, e.g. curl -X PUT -d level=debug localhost:6061/loglevel.
+func New(level zap.AtomicLevel) *Server {
+	s := &Server{mux: http.NewServeMux(), build: readBuildInfo(), published: map[string]func() any{}}
+	s.mux.HandleFunc(PprofPath, pprof.Index)
+	s.mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	s.mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	s.mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	s.mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	s.mux.Handle(VarsPath, expvar.Handler())
+	s.mux.Handle(LogLevelPath, level)
+	s.mux.HandleFunc(BuildInfoPath, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, s.build)
+	})
+	s.mux.HandleFunc("/", s.serveState)
+	return s
+}
+
+// Publish serves a JSON snapshot returned by fn at the path, e.g. /config or /cache.
+// Publishing again at the same path replaces the previous snapshot function.
+func (s *Server) Publish(path string, fn func() any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published[path] = fn
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(w, req)
+}
+
+// serveState serves published snapshots, the root path lists all endpoints.
+func (s *Server) serveState(w http.ResponseWriter, req *http.Request) {
+	s.mu.RLock()
+	fn, ok := s.published[req.URL.Path]
+	paths := make([]string, 0, len(s.published)+4)
+	for p := range s.published {
+		paths = append(paths, p)
+	}
+	s.mu.RUnlock()
+	switch {
+	case ok:
+		writeJSON(w, fn())
+	case req.URL.Path == "/":
+		paths = append(paths, BuildInfoPath, LogLevelPath, PprofPath, VarsPath)
+		sort.Strings(paths)
+		writeJSON(w, paths)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, GoVersion: runtime.Version(), StartTime: time.Now().UTC()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	s := New(level)
+	s.Publish("/config", func() any { return map[string]string{"dsn": "<redacted>"} })
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "published", method: http.MethodGet, path: "/config", wantCode: http.StatusOK, wantBody: `"dsn": "<redacted>"`},
+		{name: "index", method: http.MethodGet, path: "/", wantCode: http.StatusOK, wantBody: `"/config"`},
+		{name: "unknown", method: http.MethodGet, path: "/unknown", wantCode: http.StatusNotFound},
+		{name: "pprof", method: http.MethodGet, path: PprofPath, wantCode: http.StatusOK, wantBody: "goroutine"},
+		{name: "expvar", method: http.MethodGet, path: VarsPath, wantCode: http.StatusOK, wantBody: "memstats"},
+		{name: "log level", method: http.MethodPut, path: LogLevelPath, body: `{"level":"debug"}`, wantCode: http.StatusOK, wantBody: "debug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+}
+
+func TestBuildInfo(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(zap.NewAtomicLevel()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, BuildInfoPath, nil))
+	var info BuildInfo
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, Version, info.Version)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.False(t, info.StartTime.IsZero())
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"time"
+)
+
+// SystemPrincipal is recorded for operations performed without an authenticated principal,
+// e.g. ratings ingested from Kafka.
+const SystemPrincipal = "system"
+
+// Entry defines an audit log entry of a mutating operation.
+type Entry struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId,omitempty"`
+	Principal string    `json:"principal"`
+	Roles     []string  `json:"roles,omitempty"`
+	// Action is the operation performed, e.g. metadata.update.
+	Action string `json:"action"`
+	// Target identifies the changed resource, e.g. metadata/<id>.
+	Target string `json:"target"`
+	// Before and After are JSON snapshots of the resource, Before is empty for created resources.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Query defines audit log search criteria, empty fields match any entry.
+type Query struct {
+	Principal string
+	Action    string
+	Target    string
+	Since     time.Time
+	Until     time.Time
+	// Limit is the maximum number of entries returned, the most recent entries first.
+	Limit int
+}
+
+// Match reports whether the entry matches the query criteria other than Limit.
+func (q Query) Match(e Entry) bool {
+	return (q.Principal == "" || e.Principal == q.Principal) &&
+		(q.Action == "" || e.Action == q.Action) &&
+		(q.Target == "" || e.Target == q.Target) &&
+		(q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || e.Time.Before(q.Until))
+}
+
+// Sink defines an append-only audit log store.
+type Sink interface {
+	Write(ctx context.Context, e Entry) error
+	Query(ctx context.Context, q Query) ([]Entry, error)
+}
+
+// Logger records audit log entries to a sink.
+type Logger struct {
+	sink Sink
+	now  func() time.Time
+}
+
+// New creates a new audit logger.
+func New(sink Sink) *Logger {
+	return &Logger{sink: sink, now: time.Now}
+}
+
+// Record writes an entry for an action performed by the principal of the context.
+// Snapshots are encoded as JSON, nil snapshots are omitted. Record is a no-op on a nil logger,
+// so that callers don't need to check whether auditing is enabled.
+func (l *Logger) Record(ctx context.Context, action string, target string, before any, after any) error {
+	if l == nil {
+		return nil
+	}
+	e := Entry{
+		ID:        newID(),
+		Time:      l.now().UTC(),
+		RequestID: logging.RequestID(ctx),
+		Principal: SystemPrincipal,
+		Action:    action,
+		Target:    target,
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		e.Principal = p.Subject
+		e.Roles = p.Roles
+	}
+	var err error
+	if e.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if e.After, err = snapshot(after); err != nil {
+		return err
+	}
+	return l.sink.Write(ctx, e)
+}
+
+// Query returns entries matching the query.
+func (l *Logger) Query(ctx context.Context, q Query) ([]Entry, error) {
+	return l.sink.Query(ctx, q)
+}
+
+func snapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil, err
+	}
+	return b, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	entries []Entry
+}
+
+func (s *memorySink) Write(_ context.Context, e Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memorySink) Query(_ context.Context, q Query) ([]Entry, error) {
+	var res []Entry
+	for i := len(s.entries) - 1; i >= 0 && (q.Limit == 0 || len(res) < q.Limit); i-- {
+		if q.Match(s.entries[i]) {
+			res = append(res, s.entries[i])
+		}
+	}
+	return res, nil
+}
+
+func TestRecord(t *testing.T) {
+	sink := &memorySink{}
+	l := New(sink)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "alice", Roles: []string{"editor"}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(logging.RequestIDHeader, "req-1"))
+	type record struct {
+		Title string `json:"title"`
+	}
+	var before *record
+	assert.NoError(t, l.Record(ctx, "metadata.put", "metadata/1", before, record{"Title"}))
+	assert.NoError(t, l.Record(context.Background(), "rating.put", "rating/movie/1/bob", record{"Old"}, record{"New"}))
+
+	assert.Len(t, sink.entries, 2)
+	e := sink.entries[0]
+	assert.NotEmpty(t, e.ID)
+	e.ID = ""
+	assert.Equal(t, Entry{
+		Time:      now,
+		RequestID: "req-1",
+		Principal: "alice",
+		Roles:     []string{"editor"},
+		Action:    "metadata.put",
+		Target:    "metadata/1",
+		After:     json.RawMessage(`{"title":"Title"}`),
+	}, e)
+	assert.Equal(t, SystemPrincipal, sink.entries[1].Principal)
+	assert.Equal(t, json.RawMessage(`{"title":"Old"}`), sink.entries[1].Before)
+
+	var nilLogger *Logger
+	assert.NoError(t, nilLogger.Record(ctx, "metadata.put", "metadata/1", nil, nil))
+}
+
+func TestHandler(t *testing.T) {
+	sink := &memorySink{}
+	l := New(sink)
+	for _, p := range []string{"alice", "bob", "alice"} {
+		assert.NoError(t, l.Record(auth.NewContext(context.Background(), auth.Principal{Subject: p}), "metadata.put", "metadata/1", nil, nil))
+	}
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "all", method: http.MethodGet, url: "/v1/audit", wantStatus: http.StatusOK, wantCount: 3},
+		{name: "principal", method: http.MethodGet, url: "/v1/audit?principal=alice", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "limit", method: http.MethodGet, url: "/v1/audit?limit=1", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "invalid since", method: http.MethodGet, url: "/v1/audit?since=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodPost, url: "/v1/audit", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			l.Handler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var res struct {
+				Entries []Entry `json:"entries"`
+			}
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Len(t, res.Entries, tt.wantCount)
+		})
+	}
+}
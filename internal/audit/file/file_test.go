@@ -0,0 +1,57 @@
+package file
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	s, err := New(path)
+	assert.NoError(t, err)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{ID: "1", Time: t0, Principal: "alice", Action: "metadata.put", Target: "metadata/1"},
+		{ID: "2", Time: t0.Add(time.Minute), Principal: "bob", Action: "rating.put", Target: "rating/movie/1/bob"},
+		{ID: "3", Time: t0.Add(2 * time.Minute), Principal: "alice", Action: "metadata.update", Target: "metadata/1"},
+	}
+	for _, e := range entries {
+		assert.NoError(t, s.Write(ctx, e))
+	}
+	assert.NoError(t, s.Close())
+
+	// Entries written before are kept when the file is reopened.
+	s, err = New(path)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.NoError(t, s.Write(ctx, audit.Entry{ID: "4", Time: t0.Add(3 * time.Minute), Principal: "system", Action: "rating.put", Target: "rating/movie/2/carol"}))
+
+	tests := []struct {
+		name  string
+		query audit.Query
+		want  []string
+	}{
+		{name: "all", query: audit.Query{}, want: []string{"4", "3", "2", "1"}},
+		{name: "principal", query: audit.Query{Principal: "alice"}, want: []string{"3", "1"}},
+		{name: "target", query: audit.Query{Target: "metadata/1", Action: "metadata.put"}, want: []string{"1"}},
+		{name: "time range", query: audit.Query{Since: t0.Add(time.Minute), Until: t0.Add(3 * time.Minute)}, want: []string{"3", "2"}},
+		{name: "limit", query: audit.Query{Limit: 2}, want: []string{"4", "3"}},
+		{name: "no match", query: audit.Query{Principal: "dave"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Query(ctx, tt.query)
+			assert.NoError(t, err)
+			var ids []string
+			for _, e := range res {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
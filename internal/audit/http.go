@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Query limits of the HTTP API.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Handler returns an HTTP handler serving GET requests searching the audit log. Query parameters
+// principal, action and target filter entries, since and until are RFC 3339 timestamps and
+// limit bounds the number of entries returned. Access must be restricted to admins.
+func (l *Logger) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		q, err := parseQuery(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries, err := l.Query(req.Context(), q)
+		if err != nil {
+			http.Error(w, "audit log query failed", http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []Entry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Entries []Entry `json:"entries"`
+		}{entries})
+	})
+}
+
+func parseQuery(req *http.Request) (Query, error) {
+	v := req.URL.Query()
+	q := Query{
+		Principal: v.Get("principal"),
+		Action:    v.Get("action"),
+		Target:    v.Get("target"),
+		Limit:     DefaultLimit,
+	}
+	var err error
+	if s := v.Get("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			return Query{}, err
+		}
+	}
+	if s := v.Get("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			return Query{}, err
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return Query{}, err
+		}
+	}
+	if q.Limit <= 0 || q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	return q, nil
+}
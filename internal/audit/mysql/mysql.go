@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/mkvy/movies-app/internal/audit"
+	"github.com/mkvy/movies-app/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+var tracer = otel.Tracer("github.com/mkvy/movies-app/internal/audit/mysql")
+
+// Sink defines an audit log sink storing entries in the audit_log MySQL table.
+type Sink struct {
+	db *sql.DB
+}
+
+// New creates a new MySQL sink.
+// The dsn is in the go-sql-driver format, e.g. root:password@/movieexample.
+func New(dsn string) (*Sink, error) {
+	db, err := sql.Open("mysql", dsn+dsnParams(dsn))
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{db}, nil
+}
+
+// dsnParams enables parsing DATETIME columns into time.Time.
+func dsnParams(dsn string) string {
+	if strings.Contains(dsn, "parseTime=") {
+		return ""
+	}
+	if strings.Contains(dsn, "?") {
+		return "&parseTime=true"
+	}
+	return "?parseTime=true"
+}
+
+// Write inserts an entry.
+func (s *Sink) Write(ctx context.Context, e audit.Entry) (err error) {
+	ctx, span := startSpan(ctx, "INSERT")
+	span.SetAttributes(attribute.String("audit.action", e.Action), attribute.String("audit.target", e.Target))
+	defer tracing.End(span, &err)
+	roles, err := json.Marshal(e.Roles)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO audit_log (id, time, request_id, principal, roles, action, target, before_snapshot, after_snapshot) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		e.ID, e.Time, e.RequestID, e.Principal, string(roles), e.Action, e.Target, nullJSON(e.Before), nullJSON(e.After))
+	return err
+}
+
+// Query returns entries matching the query, the most recent entries first.
+func (s *Sink) Query(ctx context.Context, q audit.Query) (_ []audit.Entry, err error) {
+	ctx, span := startSpan(ctx, "SELECT")
+	defer tracing.End(span, &err)
+	var where []string
+	var args []any
+	for _, c := range []struct {
+		column string
+		value  string
+	}{{"principal", q.Principal}, {"action", q.Action}, {"target", q.Target}} {
+		if c.value != "" {
+			where = append(where, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, q.Until)
+	}
+	query := "SELECT id, time, request_id, principal, roles, action, target, before_snapshot, after_snapshot FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY time DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []audit.Entry
+	for rows.Next() {
+		var e audit.Entry
+		var roles string
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &e.RequestID, &e.Principal, &roles, &e.Action, &e.Target, &before, &after); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(roles), &e.Roles); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		res = append(res, e)
+	}
+	span.SetAttributes(attribute.Int("db.rows", len(res)))
+	return res, rows.Err()
+}
+
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation+" audit_log",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String("audit_log"),
+		),
+	)
+}
+
+func nullJSON(b json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(b), Valid: len(b) > 0}
+}
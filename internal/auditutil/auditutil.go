@@ -0,0 +1,80 @@
+package auditutil
+
+import (
+	"context"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/audit"
+	"github.com/mkvy/movies-app/internal/audit/file"
+	"github.com/mkvy/movies-app/internal/audit/mysql"
+	"net/http"
+)
+
+// Supported audit log sink types.
+const (
+	SinkNone  = "none"
+	SinkFile  = "file"
+	SinkMySQL = "mysql"
+)
+
+// Config defines audit log configuration.
+type Config struct {
+	// Sink is one of none, file or mysql, auditing is disabled if empty.
+	Sink string `yaml:"sink"`
+	// Path is the JSON Lines file of the file sink.
+	Path string `yaml:"path"`
+	// DSN is the database of the mysql sink, the service database is used if empty.
+	DSN string `yaml:"dsn" secret:"true"`
+}
+
+// Validate checks the audit log configuration consistency.
+func (c Config) Validate() error {
+	switch c.Sink {
+	case "", SinkNone, SinkMySQL:
+	case SinkFile:
+		if c.Path == "" {
+			return fmt.Errorf("path must be set for %s sink", c.Sink)
+		}
+	default:
+		return fmt.Errorf("unsupported sink %q, must be one of %s, %s, %s", c.Sink, SinkNone, SinkFile, SinkMySQL)
+	}
+	return nil
+}
+
+// Enabled reports whether auditing is enabled.
+func (c Config) Enabled() bool {
+	return c.Sink != "" && c.Sink != SinkNone
+}
+
+// Logger defines an audit logger recording changes and serving recorded entries.
+type Logger interface {
+	Record(ctx context.Context, action string, target string, before any, after any) error
+	Handler() http.Handler
+}
+
+// NewLogger creates an audit logger writing to the configured sink, it returns a nil interface if auditing
+// is disabled, so that it can be passed to controllers treating a nil auditor as disabled auditing.
+// The mysql sink uses defaultDSN unless a DSN is configured.
+func NewLogger(cfg Config, defaultDSN string) (Logger, error) {
+	switch cfg.Sink {
+	case "", SinkNone:
+		return nil, nil
+	case SinkFile:
+		sink, err := file.New(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return audit.New(sink), nil
+	case SinkMySQL:
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = defaultDSN
+		}
+		sink, err := mysql.New(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return audit.New(sink), nil
+	default:
+		return nil, fmt.Errorf("unsupported sink %q", cfg.Sink)
+	}
+}
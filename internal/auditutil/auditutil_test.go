@@ -0,0 +1,33 @@
+package auditutil
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "default", wantNil: true},
+		{name: "none", cfg: Config{Sink: SinkNone}, wantNil: true},
+		{name: "file", cfg: Config{Sink: SinkFile, Path: filepath.Join(t.TempDir(), "audit.jsonl")}},
+		{name: "unsupported", cfg: Config{Sink: "kafka"}, wantNil: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogger(tt.cfg, "")
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			// Controllers check for a nil auditor interface, which a nil pointer isn't.
+			var auditor interface {
+				Record(ctx context.Context, action string, target string, before any, after any) error
+			} = l
+			assert.Equal(t, tt.wantNil, auditor == nil)
+		})
+	}
+}
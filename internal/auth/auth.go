@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnauthenticated is returned when request credentials are missing or invalid.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authentication methods a principal can be authenticated with.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api-key"
+	// MethodNone is the method of Anonymous, which isn't authenticated.
+	MethodNone = "none"
+)
+
+// Anonymous is the principal of requests served while authentication is disabled.
+var Anonymous = Principal{Subject: "anonymous", Method: MethodNone}
+
+// Principal defines an authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+	// Method is the authentication method, MethodJWT or MethodAPIKey.
+	Method string
+}
+
+// HasRole reports whether the principal has the role.
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+type disabledKey struct{}
+
+// disabledContext marks the context of a request served while authentication is disabled.
+func disabledContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, disabledKey{}, true)
+}
+
+// Caller returns the principal of an authenticated request or Anonymous if the request was served
+// while authentication is disabled. It returns false if the request requires credentials it lacks.
+func Caller(ctx context.Context) (Principal, bool) {
+	if p, ok := FromContext(ctx); ok {
+		return p, true
+	}
+	if disabled, _ := ctx.Value(disabledKey{}).(bool); disabled {
+		return Anonymous, true
+	}
+	return Principal{}, false
+}
+
+// Config defines authentication configuration.
+type Config struct {
+	Enabled bool      `yaml:"enabled"`
+	JWT     JWTConfig `yaml:"jwt"`
+	// APIKeys defines static API keys.
+	APIKeys []APIKey `yaml:"apiKeys"`
+	// Public lists full gRPC method names or HTTP paths callable without credentials.
+	// A trailing * matches any suffix, e.g. /grpc.reflection.v1alpha.ServerReflection/*.
+	Public []string `yaml:"public"`
+}
+
+// JWTConfig defines JWT validation parameters. HS256 tokens are validated with Secret and
+// RS256 tokens with keys from the JWKS file, at least one of them must be set to accept JWTs.
+type JWTConfig struct {
+	Secret   string `yaml:"secret" secret:"true"`
+	JWKSFile string `yaml:"jwksFile"`
+	// Issuer and Audience are checked if set.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// Leeway allows for clock skew when checking expiration.
+	Leeway time.Duration `yaml:"leeway"`
+	// RolesClaim is the name of the claim holding principal roles, roles by default.
+	RolesClaim string `yaml:"rolesClaim"`
+}
+
+// APIKey defines a static API key and the principal it authenticates.
+type APIKey struct {
+	Key     string   `yaml:"key" secret:"true"`
+	Subject string   `yaml:"subject"`
+	Roles   []string `yaml:"roles"`
+}
+
+// Validate checks the configuration consistency.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.JWT.Secret == "" && c.JWT.JWKSFile == "" && len(c.APIKeys) == 0 {
+		return errors.New("jwt.secret, jwt.jwksFile or apiKeys must be set when authentication is enabled")
+	}
+	for i, k := range c.APIKeys {
+		if k.Key == "" || k.Subject == "" {
+			return fmt.Errorf("apiKeys[%d]: key and subject must be set", i)
+		}
+	}
+	return nil
+}
+
+// Authenticator validates request credentials.
+type Authenticator struct {
+	mu     sync.RWMutex
+	cfg    Config
+	keys   map[string]*rsa.PublicKey
+	parser *jwt.Parser
+}
+
+// New creates a new authenticator, loading the JWKS file if it is configured.
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{}
+	if err := a.Update(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Update replaces the configuration at runtime, e.g. to rotate keys.
+// The current configuration is kept if the JWKS file can't be loaded.
+func (a *Authenticator) Update(cfg Config) error {
+	var keys map[string]*rsa.PublicKey
+	if cfg.Enabled && cfg.JWT.JWKSFile != "" {
+		var err error
+		if keys, err = LoadJWKS(cfg.JWT.JWKSFile); err != nil {
+			return err
+		}
+	}
+	if cfg.JWT.RolesClaim == "" {
+		cfg.JWT.RolesClaim = "roles"
+	}
+	opts := []jwt.ParserOption{jwt.WithLeeway(cfg.JWT.Leeway), jwt.WithExpirationRequired()}
+	if cfg.JWT.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWT.Issuer))
+	}
+	if cfg.JWT.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWT.Audience))
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg = cfg
+	a.keys = keys
+	a.parser = jwt.NewParser(opts...)
+	return nil
+}
+
+// Enabled reports whether authentication is enabled.
+func (a *Authenticator) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg.Enabled
+}
+
+// Public reports whether the gRPC method or HTTP path may be called without credentials.
+func (a *Authenticator) Public(name string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, p := range a.cfg.Public {
+		if p == name || strings.HasSuffix(p, "*") && strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate returns the principal authenticated by a bearer token or an API key.
+// Errors wrap ErrUnauthenticated.
+func (a *Authenticator) Authenticate(token string, apiKey string) (Principal, error) {
+	switch {
+	case token != "":
+		return a.authenticateJWT(token)
+	case apiKey != "":
+		return a.authenticateAPIKey(apiKey)
+	}
+	return Principal{}, fmt.Errorf("%w: missing credentials", ErrUnauthenticated)
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (Principal, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, k := range a.cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+			return Principal{Subject: k.Subject, Roles: k.Roles, Method: MethodAPIKey}, nil
+		}
+	}
+	return Principal{}, fmt.Errorf("%w: invalid API key", ErrUnauthenticated)
+}
+
+func (a *Authenticator) authenticateJWT(token string) (Principal, error) {
+	a.mu.RLock()
+	cfg, keys, parser := a.cfg.JWT, a.keys, a.parser
+	a.mu.RUnlock()
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		switch t.Method {
+		case jwt.SigningMethodHS256:
+			if cfg.Secret == "" {
+				return nil, errors.New("HS256 tokens are not accepted")
+			}
+			return []byte(cfg.Secret), nil
+		case jwt.SigningMethodRS256:
+			kid, _ := t.Header["kid"].(string)
+			if key, ok := keys[kid]; ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return nil, fmt.Errorf("unsupported signing method %s", t.Method.Alg())
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return Principal{}, fmt.Errorf("%w: missing subject", ErrUnauthenticated)
+	}
+	p := Principal{Subject: sub, Method: MethodJWT}
+	switch roles := claims[cfg.RolesClaim].(type) {
+	case string:
+		p.Roles = strings.Fields(roles)
+	case []any:
+		for _, r := range roles {
+			if s, ok := r.(string); ok {
+				p.Roles = append(p.Roles, s)
+			}
+		}
+	}
+	return p, nil
+}
+
+// LoadJWKS loads RSA public keys from a JSON Web Key Set file, keyed by key id.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid exponent: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA signing keys in %s", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecret = "secret"
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	a, err := New(Config{
+		Enabled: true,
+		JWT: JWTConfig{
+			Secret:   testSecret,
+			JWKSFile: writeJWKS(t, "key-1", &rsaKey.PublicKey),
+			Issuer:   "movies",
+		},
+		APIKeys: []APIKey{{Key: "key", Subject: "service", Roles: []string{"editor"}}},
+	})
+	assert.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name    string
+		token   string
+		apiKey  string
+		want    Principal
+		wantErr bool
+	}{
+		{
+			name:  "HS256",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"sub": "user0", "iss": "movies", "exp": exp, "roles": []string{"admin"}}),
+			want:  Principal{Subject: "user0", Roles: []string{"admin"}, Method: MethodJWT},
+		},
+		{
+			name:  "RS256",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "key-1", jwt.MapClaims{"sub": "user1", "iss": "movies", "exp": exp}),
+			want:  Principal{Subject: "user1", Method: MethodJWT},
+		},
+		{
+			name:    "unknown key id",
+			token:   sign(t, jwt.SigningMethodRS256, rsaKey, "key-2", jwt.MapClaims{"sub": "user1", "iss": "movies", "exp": exp}),
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("other"), "", jwt.MapClaims{"sub": "user0", "iss": "movies", "exp": exp}),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"sub": "user0", "iss": "movies", "exp": time.Now().Add(-time.Hour).Unix()}),
+			wantErr: true,
+		},
+		{
+			name:    "without expiration",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"sub": "user0", "iss": "movies"}),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"sub": "user0", "iss": "other", "exp": exp}),
+			wantErr: true,
+		},
+		{
+			name:   "API key",
+			apiKey: "key",
+			want:   Principal{Subject: "service", Roles: []string{"editor"}, Method: MethodAPIKey},
+		},
+		{
+			name:    "invalid API key",
+			apiKey:  "other",
+			wantErr: true,
+		},
+		{
+			name:    "missing credentials",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authenticate(tt.token, tt.apiKey)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrUnauthenticated), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a, err := New(Config{
+		Enabled: true,
+		APIKeys: []APIKey{{Key: "key", Subject: "user0"}},
+		Public:  []string{"/Service/Get", "/grpc.reflection.v1alpha.ServerReflection/*"},
+	})
+	assert.NoError(t, err)
+	interceptor := a.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) {
+		p, _ := FromContext(ctx)
+		return p.Subject, nil
+	}
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyHeader, key))
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		want     any
+		wantCode codes.Code
+	}{
+		{"public", context.Background(), "/Service/Get", "", codes.OK},
+		{"public prefix", context.Background(), "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "", codes.OK},
+		{"public with credentials", withKey("key"), "/Service/Get", "user0", codes.OK},
+		{"public with invalid credentials", withKey("other"), "/Service/Get", nil, codes.Unauthenticated},
+		{"protected", withKey("key"), "/Service/Put", "user0", codes.OK},
+		{"protected without credentials", context.Background(), "/Service/Put", nil, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := New(Config{Enabled: true, JWT: JWTConfig{Secret: testSecret}})
+	assert.NoError(t, err)
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p, _ := FromContext(req.Context())
+		_, _ = w.Write([]byte(p.Subject))
+	}))
+
+	req := httptest.NewRequest(http.MethodPut, "/rating", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	token := sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"sub": "user0", "exp": time.Now().Add(time.Hour).Unix()})
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user0", rec.Body.String())
+}
+
+func TestCaller(t *testing.T) {
+	enabled, err := New(Config{Enabled: true, APIKeys: []APIKey{{Key: "key", Subject: "user0"}}})
+	assert.NoError(t, err)
+	disabled, err := New(Config{})
+	assert.NoError(t, err)
+	withKey := metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyHeader, "key"))
+
+	tests := []struct {
+		name   string
+		a      *Authenticator
+		ctx    context.Context
+		want   Principal
+		wantOK bool
+	}{
+		{name: "authenticated", a: enabled, ctx: withKey, want: Principal{Subject: "user0", Method: MethodAPIKey}, wantOK: true},
+		{name: "authentication disabled", a: disabled, ctx: context.Background(), want: Anonymous, wantOK: true},
+		{name: "not intercepted", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if tt.a != nil {
+				_, err := tt.a.UnaryServerInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/Service/Put"}, func(c context.Context, _ any) (any, error) {
+					ctx = c
+					return nil, nil
+				})
+				assert.NoError(t, err)
+			}
+			got, ok := Caller(ctx)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// Middleware returns an HTTP middleware authenticating requests and putting the principal into
+// the request context. Requests with missing or invalid credentials are rejected with 401,
+// unless the path is public and no credentials are passed.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, req.WithContext(disabledContext(req.Context())))
+			return
+		}
+		token := bearerToken(req.Header.Get(AuthorizationHeader))
+		apiKey := req.Header.Get(APIKeyHeader)
+		if token == "" && apiKey == "" && a.Public(req.URL.Path) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		p, err := a.Authenticate(token, apiKey)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="movies-app"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), p)))
+	})
+}
@@ -0,0 +1,202 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Well-known roles. Principals with RoleAdmin are allowed to do anything.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+)
+
+// ErrDenied is returned when a principal is not allowed to perform a request.
+var ErrDenied = errors.New("permission denied")
+
+// Rule defines who may call a gRPC method or an HTTP route.
+type Rule struct {
+	// Method is a full gRPC method name, e.g. /MetadataService/PutMetadata, or an HTTP route
+	// of a request method and a path, e.g. PUT /metadata. A trailing * matches any suffix.
+	Method string `yaml:"method"`
+	// AllowAnonymous allows requests without an authenticated principal.
+	AllowAnonymous bool `yaml:"allowAnonymous"`
+	// Roles lists roles allowed to call the method, any authenticated principal is allowed if empty.
+	Roles []string `yaml:"roles"`
+	// Owner is the request field holding the user the request acts on, e.g. user_id.
+	// If it is set, principals may only act on their own behalf.
+	Owner string `yaml:"owner"`
+}
+
+// Config defines an authorization policy. The first rule matching a request applies,
+// requests without a matching rule are allowed.
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+// Validate checks the configuration consistency.
+func (c Config) Validate() error {
+	for i, r := range c.Rules {
+		if r.Method == "" {
+			return fmt.Errorf("rules[%d]: method must be set", i)
+		}
+		if r.AllowAnonymous && (len(r.Roles) > 0 || r.Owner != "") {
+			return fmt.Errorf("rules[%d]: anonymous access can't be combined with roles or owner", i)
+		}
+	}
+	return nil
+}
+
+// Request defines a request to authorize.
+type Request struct {
+	// Method is a full gRPC method name or an HTTP route.
+	Method string
+	// Owner returns the value of a request field, it may be nil if the request has no fields.
+	Owner func(field string) string
+}
+
+// Enforcer authorizes requests according to a policy and logs denials.
+type Enforcer struct {
+	logger *zap.Logger
+
+	mu  sync.RWMutex
+	cfg Config
+}
+
+// New creates a new policy enforcer.
+func New(cfg Config, logger *zap.Logger) *Enforcer {
+	return &Enforcer{cfg: cfg, logger: logger}
+}
+
+// Update replaces the policy at runtime.
+func (e *Enforcer) Update(cfg Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cfg = cfg
+}
+
+// Authorize checks whether the principal of the request context may perform the request.
+// Denials are logged and returned as errors wrapping ErrDenied.
+func (e *Enforcer) Authorize(ctx context.Context, req Request) error {
+	e.mu.RLock()
+	cfg := e.cfg
+	e.mu.RUnlock()
+	if !cfg.Enabled {
+		return nil
+	}
+	p, authenticated := auth.FromContext(ctx)
+	err := decide(cfg.Rules, p, authenticated, req)
+	if err != nil {
+		e.logger.Warn("Access denied",
+			zap.String("audit", "authz"),
+			zap.String("method", req.Method),
+			zap.String("subject", p.Subject),
+			zap.Strings("roles", p.Roles),
+			zap.Error(err),
+		)
+	}
+	return err
+}
+
+func decide(rules []Rule, p auth.Principal, authenticated bool, req Request) error {
+	r, ok := match(rules, req.Method)
+	if !ok || r.AllowAnonymous {
+		return nil
+	}
+	if !authenticated {
+		return fmt.Errorf("%w: authentication required", ErrDenied)
+	}
+	if p.HasRole(RoleAdmin) {
+		return nil
+	}
+	if len(r.Roles) > 0 && !hasAny(p, r.Roles) {
+		return fmt.Errorf("%w: one of roles %s required", ErrDenied, strings.Join(r.Roles, ", "))
+	}
+	if r.Owner != "" && req.Owner != nil {
+		if owner := req.Owner(r.Owner); owner != "" && owner != p.Subject {
+			return fmt.Errorf("%w: %s must match the authenticated user", ErrDenied, r.Owner)
+		}
+	}
+	return nil
+}
+
+func match(rules []Rule, method string) (Rule, bool) {
+	for _, r := range rules {
+		if r.Method == method || strings.HasSuffix(r.Method, "*") && strings.HasPrefix(method, strings.TrimSuffix(r.Method, "*")) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func hasAny(p auth.Principal, roles []string) bool {
+	for _, r := range roles {
+		if p.HasRole(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor returns an interceptor rejecting unauthorized requests with PermissionDenied.
+// It must run after the authentication interceptor.
+func (e *Enforcer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		r := Request{Method: info.FullMethod}
+		if m, ok := req.(proto.Message); ok {
+			r.Owner = func(field string) string {
+				return protoField(m, field)
+			}
+		}
+		if err := e.Authorize(ctx, r); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream interceptor rejecting unauthorized requests with PermissionDenied.
+// Owner fields aren't checked as stream messages are received after the call is authorized.
+func (e *Enforcer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := e.Authorize(ss.Context(), Request{Method: info.FullMethod}); err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Middleware returns an HTTP middleware rejecting unauthorized requests with 403.
+// Routes are matched as "<request method> <path>" and owner fields are read from form values.
+// It must run after the authentication middleware.
+func (e *Enforcer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err := e.Authorize(req.Context(), Request{Method: req.Method + " " + req.URL.Path, Owner: req.FormValue})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func protoField(m proto.Message, name string) string {
+	msg := m.ProtoReflect()
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return ""
+	}
+	return msg.Get(fd).String()
+}
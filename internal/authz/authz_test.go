@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testConfig = Config{
+	Enabled: true,
+	Rules: []Rule{
+		{Method: "/MetadataService/GetMetadata", AllowAnonymous: true},
+		{Method: "/MetadataService/*", Roles: []string{RoleEditor}},
+		{Method: "/RatingService/PutRating", Owner: "user_id"},
+		{Method: "PUT /rating", Owner: "userId"},
+	},
+}
+
+func TestAuthorize(t *testing.T) {
+	user := &auth.Principal{Subject: "user0"}
+	editor := &auth.Principal{Subject: "editor0", Roles: []string{RoleEditor}}
+	admin := &auth.Principal{Subject: "admin0", Roles: []string{RoleAdmin}}
+	owner := func(id string) func(string) string {
+		return func(string) string { return id }
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		req       Request
+		wantErr   bool
+	}{
+		{"anonymous read", nil, Request{Method: "/MetadataService/GetMetadata"}, false},
+		{"anonymous write", nil, Request{Method: "/MetadataService/PutMetadata"}, true},
+		{"user write", user, Request{Method: "/MetadataService/PutMetadata"}, true},
+		{"editor write", editor, Request{Method: "/MetadataService/PutMetadata"}, false},
+		{"editor future write", editor, Request{Method: "/MetadataService/DeleteMetadata"}, false},
+		{"admin write", admin, Request{Method: "/MetadataService/PutMetadata"}, false},
+		{"own rating", user, Request{Method: "/RatingService/PutRating", Owner: owner("user0")}, false},
+		{"rating without user", user, Request{Method: "/RatingService/PutRating", Owner: owner("")}, false},
+		{"other user's rating", user, Request{Method: "/RatingService/PutRating", Owner: owner("user1")}, true},
+		{"admin rating for other user", admin, Request{Method: "/RatingService/PutRating", Owner: owner("user1")}, false},
+		{"unlisted method", nil, Request{Method: "/RatingService/GetAggregatedRating"}, false},
+	}
+	e := New(testConfig, zap.NewNop())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.NewContext(ctx, *tt.principal)
+			}
+			err := e.Authorize(ctx, tt.req)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrDenied), "got %v", err)
+		})
+	}
+
+	e.Update(Config{})
+	assert.NoError(t, e.Authorize(context.Background(), Request{Method: "/MetadataService/PutMetadata"}), "disabled policy")
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := New(testConfig, zap.NewNop()).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/RatingService/PutRating"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "user0"})
+
+	_, err := interceptor(ctx, &gen.PutRatingRequest{UserId: "user0"}, info, handler)
+	assert.NoError(t, err)
+	_, err = interceptor(ctx, &gen.PutRatingRequest{UserId: "user1"}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestMiddleware(t *testing.T) {
+	h := New(testConfig, zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPut, "/rating?userId=user1", nil)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{Subject: "user0"}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
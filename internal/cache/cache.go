@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+var tracer = otel.Tracer("github.com/mkvy/movies-app/internal/cache")
+
+// ErrNotFound is returned by a Backend when a key is not present in it.
+var ErrNotFound = errors.New("cache key not found")
+
+// Backend defines a shared cache store, e.g. Redis or memcached, which is consulted
+// after the in-process cache and before the loader.
+type Backend interface {
+	// Get returns a value stored under the key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores a value under the key for a given period of time.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key from the store.
+	Delete(ctx context.Context, key string) error
+}
+
+// DefaultLoadTimeout bounds loads of Config with a zero LoadTimeout.
+const DefaultLoadTimeout = 5 * time.Second
+
+// Config defines cache parameters.
+type Config struct {
+	// Size is the maximum number of entries kept in process.
+	Size int `yaml:"size"`
+	// TTL defines how long found values are kept.
+	TTL time.Duration `yaml:"ttl"`
+	// NegativeTTL defines how long not found results are kept, zero disables negative caching.
+	NegativeTTL time.Duration `yaml:"negativeTTL"`
+	// LoadTimeout bounds loads shared by coalesced callers, DefaultLoadTimeout is used if zero.
+	LoadTimeout time.Duration `yaml:"loadTimeout"`
+}
+
+// Cache defines an in-process LRU cache with TTL, negative caching and request coalescing,
+// optionally backed by a shared Backend.
+type Cache[V any] struct {
+	cfg      Config
+	backend  Backend
+	notFound error
+
+	mu           sync.Mutex
+	ll           *list.List
+	items        map[string]*list.Element
+	calls        map[string]*call[V]
+	onInvalidate []func(key string)
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	found   bool
+	expires time.Time
+}
+
+// sharedEntry is an entry representation stored in a Backend.
+type sharedEntry[V any] struct {
+	Found bool `json:"found"`
+	Value V    `json:"value,omitempty"`
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New creates a new cache. Loader errors matching notFound are cached for NegativeTTL and
+// returned as notFound on subsequent hits. Backend is optional and may be nil.
+func New[V any](cfg Config, backend Backend, notFound error) *Cache[V] {
+	return &Cache[V]{
+		cfg:      cfg,
+		backend:  backend,
+		notFound: notFound,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		calls:    map[string]*call[V]{},
+	}
+}
+
+// SetConfig changes cache parameters at runtime. New TTLs apply to entries stored afterwards,
+// entries over the new size are evicted.
+func (c *Cache[V]) SetConfig(cfg Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+	c.evict()
+}
+
+// Get returns a cached value for the key. On a miss concurrent callers of the same key are
+// coalesced into a single load call and its result is cached. The load isn't cancelled with the
+// context of the caller starting it, which other callers may be waiting for, it runs until LoadTimeout.
+func (c *Cache[V]) Get(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	ctx, span := tracer.Start(ctx, "cache.Get", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
+	if e, ok := c.lookup(key); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return e.value, c.result(e.found)
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		span.SetAttributes(attribute.Bool("cache.coalesced", true))
+		return cl.wait(ctx)
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.calls[key] = cl
+	timeout := c.cfg.LoadTimeout
+	c.mu.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultLoadTimeout
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(detached{ctx}, timeout)
+		defer cancel()
+		cl.value, cl.err = c.load(ctx, key, load)
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	return cl.wait(ctx)
+}
+
+// wait returns the result of the call unless ctx is done first.
+func (cl *call[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// detached keeps values of a context, e.g. its span, without its deadline and cancellation.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
+
+func (c *Cache[V]) load(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	if c.backend != nil {
+		if b, err := c.backend.Get(ctx, key); err == nil {
+			var se sharedEntry[V]
+			if err := json.Unmarshal(b, &se); err == nil {
+				trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.shared_hit", true))
+				c.store(key, se.Value, se.Found)
+				return se.Value, c.result(se.Found)
+			}
+		}
+	}
+	v, err := load(ctx)
+	var zero V
+	c.mu.Lock()
+	cfg := c.cfg
+	c.mu.Unlock()
+	switch {
+	case err == nil:
+		c.store(key, v, true)
+		c.share(ctx, key, sharedEntry[V]{Found: true, Value: v}, cfg.TTL)
+	case c.notFound != nil && errors.Is(err, c.notFound) && cfg.NegativeTTL > 0:
+		c.store(key, zero, false)
+		c.share(ctx, key, sharedEntry[V]{}, cfg.NegativeTTL)
+	}
+	return v, err
+}
+
+func (c *Cache[V]) result(found bool) error {
+	if found {
+		return nil
+	}
+	return c.notFound
+}
+
+func (c *Cache[V]) lookup(key string) (*entry[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry[V])
+	if time.Now().After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+func (c *Cache[V]) store(key string, v V, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.cfg.TTL
+	if !found {
+		ttl = c.cfg.NegativeTTL
+	}
+	if ttl <= 0 || c.cfg.Size <= 0 {
+		return
+	}
+	e := &entry[V]{key: key, value: v, found: found, expires: time.Now().Add(ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(e)
+	c.evict()
+}
+
+// evict removes the least recently used entries over the size limit, c.mu must be held.
+func (c *Cache[V]) evict() {
+	for c.ll.Len() > c.cfg.Size && c.ll.Len() > 0 {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*entry[V]).key)
+	}
+}
+
+func (c *Cache[V]) share(ctx context.Context, key string, se sharedEntry[V], ttl time.Duration) {
+	if c.backend == nil || ttl <= 0 {
+		return
+	}
+	b, err := json.Marshal(se)
+	if err != nil {
+		return
+	}
+	_ = c.backend.Set(ctx, key, b, ttl)
+}
+
+// Invalidate removes the key from the cache and the shared backend and notifies invalidation hooks.
+func (c *Cache[V]) Invalidate(ctx context.Context, key string) error {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+	hooks := c.onInvalidate
+	c.mu.Unlock()
+	for _, h := range hooks {
+		h(key)
+	}
+	if c.backend != nil {
+		return c.backend.Delete(ctx, key)
+	}
+	return nil
+}
+
+// OnInvalidate registers a hook called with the key each time it gets invalidated.
+func (c *Cache[V]) OnInvalidate(hook func(key string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onInvalidate = append(c.onInvalidate, hook)
+}
+
+// State defines a snapshot of the cache state, e.g. for debugging.
+type State struct {
+	Entries     int    `json:"entries"`
+	Size        int    `json:"size"`
+	TTL         string `json:"ttl"`
+	NegativeTTL string `json:"negativeTTL"`
+	// Loading is the number of keys being loaded.
+	Loading int  `json:"loading"`
+	Shared  bool `json:"shared"`
+}
+
+// State returns a snapshot of the cache state.
+func (c *Cache[V]) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return State{
+		Entries:     c.ll.Len(),
+		Size:        c.cfg.Size,
+		TTL:         c.cfg.TTL.String(),
+		NegativeTTL: c.cfg.NegativeTTL.String(),
+		Loading:     len(c.calls),
+		Shared:      c.backend != nil,
+	}
+}
+
+// Len returns the number of entries kept in process.
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
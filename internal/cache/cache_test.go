@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	c := New[string](Config{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute}, nil, errNotFound)
+	var loads int32
+	load := func(v string, err error) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			atomic.AddInt32(&loads, 1)
+			return v, err
+		}
+	}
+
+	v, err := c.Get(ctx, "a", load("va", nil))
+	assert.Equal(t, "va", v)
+	assert.NoError(t, err)
+	v, _ = c.Get(ctx, "a", load("other", nil))
+	assert.Equal(t, "va", v, "cached value")
+
+	_, err = c.Get(ctx, "missing", load("", errNotFound))
+	assert.Equal(t, errNotFound, err)
+	_, err = c.Get(ctx, "missing", load("found", nil))
+	assert.Equal(t, errNotFound, err, "negative cache")
+
+	_, err = c.Get(ctx, "failing", load("", errors.New("unexpected")))
+	assert.Error(t, err)
+	v, err = c.Get(ctx, "failing", load("recovered", nil))
+	assert.Equal(t, "recovered", v, "errors are not cached")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Len(), "size bound")
+
+	var invalidated []string
+	c.OnInvalidate(func(key string) { invalidated = append(invalidated, key) })
+	assert.NoError(t, c.Invalidate(ctx, "failing"))
+	v, _ = c.Get(ctx, "failing", load("reloaded", nil))
+	assert.Equal(t, "reloaded", v)
+	assert.Equal(t, []string{"failing"}, invalidated)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&loads))
+}
+
+func TestCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := New[int](Config{Size: 10, TTL: 10 * time.Millisecond}, nil, errNotFound)
+	v, _ := c.Get(ctx, "k", func(context.Context) (int, error) { return 1, nil })
+	assert.Equal(t, 1, v)
+	time.Sleep(20 * time.Millisecond)
+	v, _ = c.Get(ctx, "k", func(context.Context) (int, error) { return 2, nil })
+	assert.Equal(t, 2, v)
+}
+
+func TestCacheCoalescing(t *testing.T) {
+	ctx := context.Background()
+	c := New[int](Config{Size: 10, TTL: time.Minute}, nil, errNotFound)
+	var loads int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(ctx, "k", func(context.Context) (int, error) {
+				atomic.AddInt32(&loads, 1)
+				<-release
+				return 42, nil
+			})
+			assert.Equal(t, 42, v)
+			assert.NoError(t, err)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestCacheCoalescingCancellation(t *testing.T) {
+	c := New[int](Config{Size: 10, TTL: time.Minute}, nil, errNotFound)
+	release := make(chan struct{})
+	loaded := make(chan error, 1)
+	load := func(ctx context.Context) (int, error) {
+		<-release
+		loaded <- ctx.Err()
+		return 42, nil
+	}
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.Get(leaderCtx, "k", load)
+		leaderErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	waiter := make(chan int, 1)
+	go func() {
+		v, _ := c.Get(context.Background(), "k", load)
+		waiter <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	close(release)
+	assert.NoError(t, <-loaded, "the shared load outlives the leading caller")
+	assert.Equal(t, 42, <-waiter)
+}
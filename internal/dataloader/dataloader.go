@@ -0,0 +1,132 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Defaults of loader options.
+const (
+	DefaultWait     = 2 * time.Millisecond
+	DefaultMaxBatch = 100
+)
+
+// BatchFunc loads values of keys, keys missing from the result are not found.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+// Loader batches and deduplicates loads of values by key. Keys requested within the wait window
+// are loaded with a single call of the batch function and each key is loaded at most once,
+// so a loader is meant to be scoped to a single request, e.g. a GraphQL query.
+type Loader[K comparable, V any] struct {
+	ctx      context.Context
+	fn       BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	results map[K]*result[V]
+	pending []K
+	timer   *time.Timer
+}
+
+// New creates a new loader calling fn with ctx, e.g. the request context. Batches are dispatched
+// once wait elapses since the first key of a batch is requested or maxBatch keys are requested.
+// Defaults are used for zero values.
+func New[K comparable, V any](ctx context.Context, fn BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	if wait == 0 {
+		wait = DefaultWait
+	}
+	if maxBatch == 0 {
+		maxBatch = DefaultMaxBatch
+	}
+	return &Loader[K, V]{ctx: ctx, fn: fn, wait: wait, maxBatch: maxBatch, results: map[K]*result[V]{}}
+}
+
+// Load returns the value of the key and whether it is found.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	r := l.request(key)
+	select {
+	case <-r.done:
+		return r.value, r.found, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+// Prime stores the value of the key unless it is already requested, e.g. values returned by a search,
+// so that they aren't loaded again.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.results[key]; ok {
+		return
+	}
+	r := &result[V]{done: make(chan struct{}), value: value, found: true}
+	close(r.done)
+	l.results[key] = r
+}
+
+// Clear forgets the value of the key, e.g. once it is changed, so that the next load requests it again.
+func (l *Loader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.results, key)
+}
+
+func (l *Loader[K, V]) request(key K) *result[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r, ok := l.results[key]; ok {
+		return r
+	}
+	r := &result[V]{done: make(chan struct{})}
+	l.results[key] = r
+	l.pending = append(l.pending, key)
+	switch {
+	case len(l.pending) >= l.maxBatch:
+		l.dispatchLocked()
+	case len(l.pending) == 1:
+		l.timer = time.AfterFunc(l.wait, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.dispatchLocked()
+		})
+	}
+	return r
+}
+
+// dispatchLocked loads the pending batch in the background.
+func (l *Loader[K, V]) dispatchLocked() {
+	if len(l.pending) == 0 {
+		return
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	keys := l.pending
+	l.pending = nil
+	// Results are kept by key as the batch function may reorder keys.
+	results := make(map[K]*result[V], len(keys))
+	for _, k := range keys {
+		results[k] = l.results[k]
+	}
+	go func() {
+		values, err := l.fn(l.ctx, keys)
+		for k, r := range results {
+			r.err = err
+			if err == nil {
+				r.value, r.found = values[k]
+			}
+			close(r.done)
+		}
+	}()
+}
@@ -0,0 +1,104 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *recorder) load(_ context.Context, keys []string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Keys are sorted in place, loaders must not depend on their order.
+	sort.Strings(keys)
+	r.batches = append(r.batches, append([]string(nil), keys...))
+	res := map[string]string{}
+	for _, k := range keys {
+		if k != "missing" {
+			res[k] = "value-" + k
+		}
+	}
+	return res, r.err
+}
+
+func loadAll(l *Loader[string, string], keys ...string) []string {
+	res := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		wg.Add(1)
+		go func(i int, k string) {
+			defer wg.Done()
+			v, found, err := l.Load(context.Background(), k)
+			switch {
+			case err != nil:
+				res[i] = err.Error()
+			case !found:
+				res[i] = "not found"
+			default:
+				res[i] = v
+			}
+		}(i, k)
+	}
+	wg.Wait()
+	return res
+}
+
+func TestLoader(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBatch    int
+		err         error
+		keys        []string
+		wantValues  []string
+		wantBatches [][]string
+	}{
+		{
+			name:        "batched and deduplicated",
+			keys:        []string{"a", "b", "a", "missing"},
+			wantValues:  []string{"value-a", "value-b", "value-a", "not found"},
+			wantBatches: [][]string{{"a", "b", "missing"}},
+		},
+		{
+			name:        "max batch",
+			maxBatch:    1,
+			keys:        []string{"a", "a"},
+			wantValues:  []string{"value-a", "value-a"},
+			wantBatches: [][]string{{"a"}},
+		},
+		{
+			name:        "error",
+			err:         errors.New("unavailable"),
+			keys:        []string{"a", "b"},
+			wantValues:  []string{"unavailable", "unavailable"},
+			wantBatches: [][]string{{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{err: tt.err}
+			l := New[string, string](context.Background(), r.load, 10*time.Millisecond, tt.maxBatch)
+			assert.Equal(t, tt.wantValues, loadAll(l, tt.keys...))
+			assert.Equal(t, tt.wantBatches, r.batches)
+		})
+	}
+}
+
+func TestPrime(t *testing.T) {
+	r := &recorder{}
+	l := New[string, string](context.Background(), r.load, 0, 0)
+	l.Prime("a", "primed")
+	assert.Equal(t, []string{"primed", "value-b"}, loadAll(l, "a", "b"))
+	l.Clear("a")
+	assert.Equal(t, []string{"value-a"}, loadAll(l, "a"))
+	assert.Equal(t, [][]string{{"b"}, {"a"}}, r.batches)
+}
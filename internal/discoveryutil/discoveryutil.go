@@ -0,0 +1,71 @@
+package discoveryutil
+
+import (
+	"context"
+	"fmt"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/consul"
+	"github.com/mkvy/movies-app/pkg/discovery/dns"
+	"github.com/mkvy/movies-app/pkg/discovery/file"
+	"github.com/mkvy/movies-app/pkg/discovery/static"
+	"time"
+)
+
+// Supported registry types.
+const (
+	TypeConsul = "consul"
+	TypeStatic = "static"
+	TypeFile   = "file"
+	TypeDNS    = "dns"
+)
+
+const defaultConsulAddr = "localhost:8500"
+
+// Config defines service registry configuration.
+type Config struct {
+	// Type is one of consul, static, file or dns, consul is used if empty.
+	Type string `yaml:"type"`
+	// Address is the Consul agent address.
+	Address string `yaml:"address"`
+	// Path is the static or file registry endpoints file.
+	Path string `yaml:"path"`
+	// ReloadInterval defines how often the file registry checks the endpoints file for changes.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+	// DNS defines DNS registry settings.
+	DNS dns.Config `yaml:"dns"`
+}
+
+// Validate checks the registry configuration consistency.
+func (c Config) Validate() error {
+	switch c.Type {
+	case "", TypeConsul, TypeDNS:
+	case TypeStatic, TypeFile:
+		if c.Path == "" {
+			return fmt.Errorf("path must be set for %s registry", c.Type)
+		}
+	default:
+		return fmt.Errorf("unsupported registry type %q, must be one of %s, %s, %s, %s", c.Type, TypeConsul, TypeStatic, TypeFile, TypeDNS)
+	}
+	return nil
+}
+
+// NewRegistry creates a service registry of the configured type.
+// A file registry keeps watching its endpoints file until ctx is done.
+func NewRegistry(ctx context.Context, cfg Config) (discovery.Registry, error) {
+	switch cfg.Type {
+	case "", TypeConsul:
+		addr := cfg.Address
+		if addr == "" {
+			addr = defaultConsulAddr
+		}
+		return consul.NewRegistry(addr)
+	case TypeStatic:
+		return static.NewRegistryFromFile(cfg.Path)
+	case TypeFile:
+		return file.NewRegistry(ctx, cfg.Path, cfg.ReloadInterval)
+	case TypeDNS:
+		return dns.NewRegistry(cfg.DNS, nil)
+	default:
+		return nil, fmt.Errorf("unsupported registry type %q", cfg.Type)
+	}
+}
@@ -2,18 +2,52 @@ package grpcutil
 
 import (
 	"context"
+	"github.com/mkvy/movies-app/internal/metrics"
 	"github.com/mkvy/movies-app/pkg/discovery"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"math/rand"
 )
 
-// ServiceConnection attempts to select a random service instance and returns a gRPC connection to it.
-func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
-	addrs, err := registry.ServiceAddresses(ctx, serviceName)
+// ServiceAddresses returns addresses of instances of the service serving gRPC, skipping e.g. HTTP instances.
+// Instances registered without a protocol are assumed to serve gRPC.
+func ServiceAddresses(ctx context.Context, serviceName string, registry discovery.Registry) ([]string, error) {
+	instances, err := registry.ServiceInstances(ctx, serviceName, discovery.Filter{})
 	if err != nil {
 		return nil, err
 	}
-	return grpc.Dial(addrs[rand.Intn(len(addrs))], grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()))
+	var addrs []string
+	for _, i := range instances {
+		if i.Protocol == "" || i.Protocol == discovery.ProtocolGRPC {
+			addrs = append(addrs, i.HostPort)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return addrs, nil
+}
+
+// ServiceConnection attempts to select a random gRPC instance of the service and returns a gRPC connection to it.
+// Plaintext connections are used if creds is nil.
+func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	addrs, err := ServiceAddresses(ctx, serviceName, registry)
+	if err != nil {
+		return nil, err
+	}
+	return InstanceConnection(addrs[rand.Intn(len(addrs))], creds)
+}
+
+// InstanceConnection returns a gRPC connection to a service instance with the given address.
+// Plaintext connections are used if creds is nil.
+func InstanceConnection(addr string, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	return grpc.Dial(addr, grpc.WithTransportCredentials(creds), grpc.WithChainUnaryInterceptor(
+		otelgrpc.UnaryClientInterceptor(),
+		metrics.UnaryClientInterceptor(),
+	), grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()))
 }
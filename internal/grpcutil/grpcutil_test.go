@@ -0,0 +1,40 @@
+package grpcutil
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestServiceAddresses(t *testing.T) {
+	ctx := context.Background()
+	r := memory.NewRegistry()
+	for _, i := range []discovery.Instance{
+		{ID: "metadata-1", ServiceName: "metadata", HostPort: "localhost:8081", Protocol: discovery.ProtocolGRPC},
+		{ID: "metadata-1-http", ServiceName: "metadata", HostPort: "localhost:9081", Protocol: discovery.ProtocolHTTP},
+		{ID: "metadata-2", ServiceName: "metadata", HostPort: "localhost:8082"},
+		{ID: "rating-1-http", ServiceName: "rating", HostPort: "localhost:9082", Protocol: discovery.ProtocolHTTP},
+	} {
+		assert.NoError(t, r.RegisterInstance(ctx, i))
+	}
+
+	tests := []struct {
+		name    string
+		service string
+		want    []string
+		wantErr error
+	}{
+		{name: "grpc and unspecified protocols", service: "metadata", want: []string{"localhost:8081", "localhost:8082"}},
+		{name: "http only", service: "rating", wantErr: discovery.ErrNotFound},
+		{name: "unknown", service: "movie", wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := ServiceAddresses(ctx, tt.service, r)
+		sort.Strings(got)
+		assert.Equal(t, tt.want, got, tt.name)
+		assert.Equal(t, tt.wantErr, err, tt.name)
+	}
+}
@@ -0,0 +1,193 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP probe paths.
+const (
+	LivePath  = "/healthz/live"
+	ReadyPath = "/healthz/ready"
+)
+
+// Checker checks whether a dependency is available.
+type Checker func(ctx context.Context) error
+
+// Result defines the outcome of a dependency check.
+type Result struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report defines the outcome of the last readiness evaluation.
+type Report struct {
+	// Ready is true if the instance is serving and all checks pass.
+	Ready  bool      `json:"ready"`
+	Checks []Result  `json:"checks,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+type check struct {
+	name string
+	fn   Checker
+}
+
+// Monitor evaluates dependency checks and publishes the readiness of an instance
+// through the grpc.health.v1 service and the readiness probe.
+type Monitor struct {
+	timeout  time.Duration
+	services []string
+	server   *health.Server
+	serving  atomic.Bool
+	report   atomic.Pointer[Report]
+
+	mu     sync.Mutex
+	checks []check
+}
+
+// New creates a new monitor. Each check is given the timeout to complete. The readiness is reported
+// for the overall server and for each of services, e.g. the service name. Instances are not ready
+// until SetServing(true) is called.
+func New(timeout time.Duration, services ...string) *Monitor {
+	m := &Monitor{timeout: timeout, services: append([]string{""}, services...), server: health.NewServer()}
+	m.report.Store(&Report{})
+	m.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return m
+}
+
+// Server returns the grpc.health.v1 server to register with the gRPC server.
+func (m *Monitor) Server() healthpb.HealthServer {
+	return m.server
+}
+
+// Add registers a check which must pass for the instance to be ready.
+func (m *Monitor) Add(name string, fn Checker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, check{name, fn})
+}
+
+// SetServing marks the instance as serving requests. Instances which aren't serving,
+// e.g. shutting down ones, are reported not ready right away.
+func (m *Monitor) SetServing(serving bool) {
+	m.serving.Store(serving)
+	if !serving {
+		m.report.Store(&Report{Time: time.Now()})
+		m.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Check runs all checks concurrently and publishes the resulting report.
+func (m *Monitor) Check(ctx context.Context) Report {
+	m.mu.Lock()
+	checks := append([]check(nil), m.checks...)
+	m.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = Result{Name: c.name}
+			if err := m.run(ctx, c.fn); err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	r := Report{Ready: m.serving.Load(), Checks: results, Time: time.Now()}
+	for _, res := range results {
+		if res.Error != "" {
+			r.Ready = false
+		}
+	}
+	// A concurrent SetServing(false) takes precedence over the report of checks started before it.
+	if !m.serving.Load() {
+		r.Ready = false
+	}
+	m.report.Store(&r)
+	if r.Ready {
+		m.setStatus(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		m.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return r
+}
+
+// run runs the check with the timeout. Checks of clients which don't honor the context
+// are abandoned once the timeout expires.
+func (m *Monitor) run(ctx context.Context, fn Checker) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Report returns the report of the last evaluation.
+func (m *Monitor) Report() Report {
+	return *m.report.Load()
+}
+
+// Shutdown reports all services as not serving and ignores further updates.
+func (m *Monitor) Shutdown() {
+	m.serving.Store(false)
+	m.server.Shutdown()
+}
+
+func (m *Monitor) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, s := range m.services {
+		m.server.SetServingStatus(s, status)
+	}
+}
+
+// ReadyHandler returns the readiness probe handler. It responds with the last report
+// and 200 if the instance is ready or 503 otherwise, checks aren't run per request.
+func (m *Monitor) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := m.Report()
+		w.Header().Set("Content-Type", "application/json")
+		if !r.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(r)
+	})
+}
+
+// LiveHandler returns the liveness probe handler. It responds with 200 as long as the process
+// serves HTTP requests, dependencies aren't checked so that their outages don't restart instances.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+	})
+}
+
+// ServiceLookup returns a checker passing if the registry returns addresses of the service,
+// e.g. an upstream one.
+func ServiceLookup(registry discovery.Registry, serviceName string) Checker {
+	return func(ctx context.Context) error {
+		if _, err := registry.ServiceAddresses(ctx, serviceName); err != nil {
+			return fmt.Errorf("look up %s: %w", serviceName, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	// blocking ignores the context like clients without context support.
+	blocking := func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+	tests := []struct {
+		name       string
+		serving    bool
+		checks     map[string]Checker
+		wantReady  bool
+		wantFailed []string
+	}{
+		{name: "not serving", serving: false, wantReady: false},
+		{name: "no checks", serving: true, wantReady: true},
+		{name: "passing", serving: true, checks: map[string]Checker{"db": func(context.Context) error { return nil }}, wantReady: true},
+		{name: "failing", serving: true, checks: map[string]Checker{"db": failing}, wantReady: false, wantFailed: []string{"db"}},
+		{name: "timed out", serving: true, checks: map[string]Checker{"kafka": blocking}, wantReady: false, wantFailed: []string{"kafka"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(10*time.Millisecond, "metadata")
+			for name, c := range tt.checks {
+				m.Add(name, c)
+			}
+			m.SetServing(tt.serving)
+			r := m.Check(context.Background())
+			assert.Equal(t, tt.wantReady, r.Ready)
+			var failed []string
+			for _, c := range r.Checks {
+				if c.Error != "" {
+					failed = append(failed, c.Name)
+				}
+			}
+			assert.Equal(t, tt.wantFailed, failed)
+			assert.Equal(t, r, m.Report())
+
+			want := healthpb.HealthCheckResponse_NOT_SERVING
+			if tt.wantReady {
+				want = healthpb.HealthCheckResponse_SERVING
+			}
+			for _, service := range []string{"", "metadata"} {
+				resp, err := m.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+				assert.NoError(t, err)
+				assert.Equal(t, want, resp.Status, "service %q", service)
+			}
+			w := httptest.NewRecorder()
+			m.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+			if tt.wantReady {
+				assert.Equal(t, http.StatusOK, w.Code)
+			} else {
+				assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			}
+		})
+	}
+}
+
+func TestServiceLookup(t *testing.T) {
+	ctx := context.Background()
+	registry := memory.NewRegistry()
+	check := ServiceLookup(registry, "rating")
+	assert.Error(t, check(ctx))
+	assert.NoError(t, registry.Register(ctx, "rating-1", "rating", "localhost:8082"))
+	defer registry.Deregister(ctx, "rating-1", "rating")
+	assert.NoError(t, check(ctx))
+}
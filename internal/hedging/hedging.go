@@ -0,0 +1,225 @@
+package hedging
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config defines request hedging parameters.
+type Config struct {
+	// Percentile of observed latencies after which a hedged request is sent, e.g. 0.95.
+	Percentile float64 `yaml:"percentile"`
+	// MinDelay and MaxDelay bound the computed hedging delay.
+	MinDelay time.Duration `yaml:"minDelay"`
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// BudgetRatio is the share of requests which are allowed to be hedged, e.g. 0.1.
+	BudgetRatio float64 `yaml:"budgetRatio"`
+	// BudgetBurst caps the number of hedges which can be accumulated while traffic is quiet.
+	BudgetBurst float64 `yaml:"budgetBurst"`
+	// WindowSize is the number of recent latency samples used to compute the percentile.
+	WindowSize int `yaml:"windowSize"`
+}
+
+// DefaultConfig returns hedging configuration with reasonable defaults.
+func DefaultConfig() Config {
+	return Config{
+		Percentile:  0.95,
+		MinDelay:    5 * time.Millisecond,
+		MaxDelay:    500 * time.Millisecond,
+		BudgetRatio: 0.1,
+		BudgetBurst: 10,
+		WindowSize:  1000,
+	}
+}
+
+// minSamples is the number of samples required before the observed percentile is used.
+const minSamples = 20
+
+// Hedger keeps latency statistics and a hedging budget for a single upstream service.
+// A nil Hedger is valid and disables hedging.
+type Hedger struct {
+	cfg Config
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	tokens  float64
+}
+
+// New creates a new hedger.
+func New(cfg Config) *Hedger {
+	cfg = normalize(cfg)
+	return &Hedger{cfg: cfg, samples: make([]time.Duration, 0, cfg.WindowSize), tokens: cfg.BudgetBurst}
+}
+
+// Update changes hedging parameters at runtime. Collected latency samples are kept
+// unless the window size changes.
+func (h *Hedger) Update(cfg Config) {
+	cfg = normalize(cfg)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cfg.WindowSize != h.cfg.WindowSize {
+		h.samples = make([]time.Duration, 0, cfg.WindowSize)
+		h.next = 0
+	}
+	if h.tokens > cfg.BudgetBurst {
+		h.tokens = cfg.BudgetBurst
+	}
+	h.cfg = cfg
+}
+
+// normalize replaces invalid parameters with defaults.
+func normalize(cfg Config) Config {
+	def := DefaultConfig()
+	if cfg.Percentile <= 0 || cfg.Percentile >= 1 {
+		cfg.Percentile = def.Percentile
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = def.MaxDelay
+	}
+	if cfg.MinDelay > cfg.MaxDelay {
+		cfg.MinDelay = cfg.MaxDelay
+	}
+	if cfg.BudgetBurst <= 0 {
+		cfg.BudgetBurst = def.BudgetBurst
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = def.WindowSize
+	}
+	return cfg
+}
+
+// Delay returns the time to wait for the first instance before hedging the request.
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < minSamples {
+		return h.cfg.MaxDelay
+	}
+	sorted := make([]time.Duration, len(h.samples))
+	copy(sorted, h.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	d := sorted[int(float64(len(sorted)-1)*h.cfg.Percentile)]
+	if d < h.cfg.MinDelay {
+		return h.cfg.MinDelay
+	}
+	if d > h.cfg.MaxDelay {
+		return h.cfg.MaxDelay
+	}
+	return d
+}
+
+// State defines a snapshot of the hedger state, e.g. for debugging.
+type State struct {
+	Delay   string  `json:"delay"`
+	Samples int     `json:"samples"`
+	Budget  float64 `json:"budget"`
+}
+
+// State returns a snapshot of the hedger state.
+func (h *Hedger) State() State {
+	delay := h.Delay()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return State{Delay: delay.String(), Samples: len(h.samples), Budget: h.tokens}
+}
+
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < h.cfg.WindowSize {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % h.cfg.WindowSize
+}
+
+// deposit adds the budget earned by a single request.
+func (h *Hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.cfg.BudgetRatio
+	if h.tokens > h.cfg.BudgetBurst {
+		h.tokens = h.cfg.BudgetBurst
+	}
+}
+
+// withdraw reports whether the budget allows one more hedged request.
+func (h *Hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+type result[T any] struct {
+	v       T
+	err     error
+	elapsed time.Duration
+}
+
+// Do calls a random instance out of addrs and, if it doesn't answer within the hedging delay,
+// sends the same request to another instance. The first successful response wins and the
+// other call is cancelled. Only idempotent calls must be passed to Do.
+// It returns discovery.ErrNotFound if addrs is empty, e.g. once heartbeats of all instances expired.
+func Do[T any](ctx context.Context, h *Hedger, addrs []string, call func(ctx context.Context, addr string) (T, error)) (T, error) {
+	if len(addrs) == 0 {
+		var zero T
+		return zero, discovery.ErrNotFound
+	}
+	perm := rand.Perm(len(addrs))
+	if h == nil || len(addrs) < 2 {
+		start := time.Now()
+		v, err := call(ctx, addrs[perm[0]])
+		if h != nil && err == nil {
+			h.observe(time.Since(start))
+		}
+		return v, err
+	}
+	h.deposit()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result[T], 2)
+	launch := func(addr string) {
+		start := time.Now()
+		v, err := call(ctx, addr)
+		results <- result[T]{v, err, time.Since(start)}
+	}
+	go launch(addrs[perm[0]])
+	inflight := 1
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	hedged := false
+
+	var zero T
+	for {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-timer.C:
+			if !hedged && h.withdraw() {
+				hedged = true
+				inflight++
+				go launch(addrs[perm[1]])
+			}
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				h.observe(r.elapsed)
+				return r.v, nil
+			}
+			if inflight == 0 {
+				return zero, r.err
+			}
+		}
+	}
+}
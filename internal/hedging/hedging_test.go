@@ -0,0 +1,87 @@
+package hedging
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name    string
+		delays  map[string]time.Duration
+		errs    map[string]error
+		budget  float64
+		wantRes string
+		wantErr error
+	}{
+		{
+			name:    "single instance",
+			delays:  map[string]time.Duration{"a": 0},
+			budget:  10,
+			wantRes: "a",
+		},
+		{
+			name:    "slow instance is hedged",
+			delays:  map[string]time.Duration{"slow": time.Second, "fast": 0},
+			budget:  10,
+			wantRes: "fast",
+		},
+		{
+			name:    "both instances fail",
+			delays:  map[string]time.Duration{"a": 0, "b": 0},
+			errs:    map[string]error{"a": errors.New("failed"), "b": errors.New("failed")},
+			budget:  10,
+			wantErr: errors.New("failed"),
+		},
+		{
+			name:    "no instances",
+			budget:  10,
+			wantErr: discovery.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(Config{MaxDelay: 10 * time.Millisecond, BudgetBurst: tt.budget})
+			var addrs []string
+			for addr := range tt.delays {
+				addrs = append(addrs, addr)
+			}
+			for i := 0; i < 5; i++ {
+				res, err := Do(context.Background(), h, addrs, func(ctx context.Context, addr string) (string, error) {
+					select {
+					case <-time.After(tt.delays[addr]):
+					case <-ctx.Done():
+						return "", ctx.Err()
+					}
+					return addr, tt.errs[addr]
+				})
+				assert.Equal(t, tt.wantRes, res, tt.name)
+				assert.Equal(t, tt.wantErr, err, tt.name)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	h := New(Config{MaxDelay: time.Millisecond, BudgetRatio: 0.5, BudgetBurst: 1})
+	assert.True(t, h.withdraw())
+	assert.False(t, h.withdraw())
+	h.deposit()
+	assert.False(t, h.withdraw())
+	h.deposit()
+	assert.True(t, h.withdraw())
+}
+
+func TestDelay(t *testing.T) {
+	h := New(Config{MinDelay: 2 * time.Millisecond, MaxDelay: 100 * time.Millisecond, Percentile: 0.5})
+	assert.Equal(t, 100*time.Millisecond, h.Delay())
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, h.Delay())
+}
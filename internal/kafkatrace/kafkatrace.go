@@ -0,0 +1,87 @@
+package kafkatrace
+
+import (
+	"context"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mkvy/movies-app/internal/kafkatrace"
+
+// HeaderCarrier adapts Kafka message headers to a trace context carrier.
+type HeaderCarrier struct {
+	Message *kafka.Message
+}
+
+// Get returns the value of the last header with the key.
+func (c HeaderCarrier) Get(key string) string {
+	for i := len(c.Message.Headers) - 1; i >= 0; i-- {
+		if c.Message.Headers[i].Key == key {
+			return string(c.Message.Headers[i].Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces headers with the key.
+func (c HeaderCarrier) Set(key string, value string) {
+	headers := c.Message.Headers[:0]
+	for _, h := range c.Message.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	c.Message.Headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys returns the header keys.
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.Message.Headers))
+	for _, h := range c.Message.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// StartProducerSpan starts a span of producing the message and injects its context
+// into the message headers with the global propagator.
+func StartProducerSpan(ctx context.Context, msg *kafka.Message) (context.Context, trace.Span) {
+	topic := topicOf(msg)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKey.String(topic),
+			semconv.MessagingDestinationKindTopic,
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier{msg})
+	return ctx, span
+}
+
+// StartConsumerSpan starts a span of processing the message as a child of the producer span
+// extracted from the message headers with the global propagator.
+func StartConsumerSpan(ctx context.Context, msg *kafka.Message, consumerGroup string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier{msg})
+	topic := topicOf(msg)
+	return otel.Tracer(tracerName).Start(ctx, topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKey.String(topic),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingOperationProcess,
+			semconv.MessagingKafkaConsumerGroupKey.String(consumerGroup),
+			semconv.MessagingKafkaPartitionKey.Int(int(msg.TopicPartition.Partition)),
+		),
+	)
+}
+
+func topicOf(msg *kafka.Message) string {
+	if msg.TopicPartition.Topic == nil {
+		return ""
+	}
+	return *msg.TopicPartition.Topic
+}
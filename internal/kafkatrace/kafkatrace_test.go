@@ -0,0 +1,42 @@
+package kafkatrace
+
+import (
+	"context"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	topic := "ratings"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2},
+		Headers:        []kafka.Header{{Key: "traceparent", Value: []byte("stale")}, {Key: "source", Value: []byte("test")}},
+	}
+	_, producerSpan := StartProducerSpan(context.Background(), msg)
+	producerSpan.End()
+	assert.Len(t, msg.Headers, 2, "stale trace context is replaced")
+	assert.Equal(t, "test", HeaderCarrier{msg}.Get("source"))
+
+	ctx, consumerSpan := StartConsumerSpan(context.Background(), msg, "rating")
+	consumerSpan.End()
+	producer, consumer := producerSpan.SpanContext(), trace.SpanContextFromContext(ctx)
+	assert.Equal(t, producer.TraceID(), consumer.TraceID())
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "ratings publish", spans[0].Name())
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind())
+	assert.Equal(t, "ratings process", spans[1].Name())
+	assert.Equal(t, trace.SpanKindConsumer, spans[1].SpanKind())
+	assert.Equal(t, producer.SpanID(), spans[1].Parent().SpanID())
+}
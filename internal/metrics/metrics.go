@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"time"
+)
+
+// Path is the HTTP path metrics are exposed on.
+const Path = "/metrics"
+
+// Results of repository operations, discovery lookups and ingested messages.
+const (
+	ResultOK       = "ok"
+	ResultNotFound = "not_found"
+	ResultError    = "error"
+)
+
+// Collectors are shared by all services, so that metric names and labels are the same across services.
+// Each metric carries a service label added on registration. gRPC request rates and error rates are
+// derived from the _count series of the duration histograms.
+var (
+	grpcServerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_request_duration_seconds",
+		Help:    "Duration of gRPC requests handled by the server, by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	grpcClientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_request_duration_seconds",
+		Help:    "Duration of gRPC requests sent to other services, by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	repositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_operation_duration_seconds",
+		Help:    "Duration of repository operations, by store, operation and result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"store", "operation", "result"})
+	ingestedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Number of Kafka messages consumed, by topic and result.",
+	}, []string{"topic", "result"})
+	consumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag_messages",
+		Help: "Number of Kafka messages not consumed yet, by topic and partition.",
+	}, []string{"topic", "partition"})
+	discoveryLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "discovery_lookups_total",
+		Help: "Number of service registry lookups, by looked up service and result.",
+	}, []string{"target", "result"})
+	discoveryInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "discovery_instances",
+		Help: "Number of instances returned by the last service registry lookup, by looked up service.",
+	}, []string{"target"})
+)
+
+// NewRegistry creates a registry of all metrics labelled with the service name, including Go runtime
+// and process metrics.
+func NewRegistry(serviceName string) (*prometheus.Registry, error) {
+	reg := prometheus.NewRegistry()
+	wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"service": serviceName}, reg)
+	for _, c := range []prometheus.Collector{
+		grpcServerDuration,
+		grpcClientDuration,
+		repositoryDuration,
+		ingestedMessages,
+		consumerLag,
+		discoveryLookups,
+		discoveryInstances,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	} {
+		if err := wrapped.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// Handler returns an HTTP handler exposing metrics of the registry.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
+
+// UnaryServerInterceptor returns an interceptor observing durations of handled requests.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(grpcServerDuration, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream interceptor observing durations of handled streams.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(grpcServerDuration, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor observing durations of sent requests.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observe(grpcClientDuration, method, start, err)
+		return err
+	}
+}
+
+func observe(h *prometheus.HistogramVec, method string, start time.Time, err error) {
+	h.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRepository records the duration of a repository operation started at start. It is meant to be
+// deferred with a pointer to the named error result. Errors wrapping notFound are counted as not found.
+func ObserveRepository(store string, operation string, start time.Time, err *error, notFound error) {
+	result := ResultOK
+	switch {
+	case *err == nil:
+	case notFound != nil && errors.Is(*err, notFound):
+		result = ResultNotFound
+	default:
+		result = ResultError
+	}
+	repositoryDuration.WithLabelValues(store, operation, result).Observe(time.Since(start).Seconds())
+}
+
+// IncConsumed counts a consumed Kafka message with the result of its processing.
+func IncConsumed(topic string, result string) {
+	ingestedMessages.WithLabelValues(topic, result).Inc()
+}
+
+// SetConsumerLag sets the number of messages of a Kafka topic partition not consumed yet.
+func SetConsumerLag(topic string, partition string, lag int64) {
+	consumerLag.WithLabelValues(topic, partition).Set(float64(lag))
+}
+
+// Registry defines a service registry recording results of lookups.
+type Registry struct {
+	discovery.Registry
+}
+
+// InstrumentRegistry wraps a service registry to record results of lookups.
+func InstrumentRegistry(registry discovery.Registry) *Registry {
+	return &Registry{registry}
+}
+
+// ServiceAddresses returns addresses of active instances of the service and records the lookup.
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	addrs, err := r.Registry.ServiceAddresses(ctx, serviceName)
+	observeLookup(serviceName, len(addrs), err)
+	return addrs, err
+}
+
+// ServiceInstances returns active instances of the service matching the filter and records the lookup.
+func (r *Registry) ServiceInstances(ctx context.Context, serviceName string, filter discovery.Filter) ([]discovery.Instance, error) {
+	instances, err := r.Registry.ServiceInstances(ctx, serviceName, filter)
+	observeLookup(serviceName, len(instances), err)
+	return instances, err
+}
+
+func observeLookup(serviceName string, instances int, err error) {
+	result := ResultOK
+	switch {
+	case errors.Is(err, discovery.ErrNotFound):
+		result = ResultNotFound
+	case err != nil:
+		result = ResultError
+	}
+	discoveryLookups.WithLabelValues(serviceName, result).Inc()
+	if err == nil || result == ResultNotFound {
+		discoveryInstances.WithLabelValues(serviceName).Set(float64(instances))
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+// value returns the value of a counter or gauge or the sample count of a histogram with the labels.
+func value(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.Counter != nil:
+				return m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				return m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				return float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	reg, err := NewRegistry("test")
+	assert.NoError(t, err)
+
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/TestService/Get"}
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Equal(t, float64(1), value(t, reg, "grpc_server_request_duration_seconds", map[string]string{"service": "test", "method": "/TestService/Get", "code": "OK"}))
+	assert.Equal(t, float64(1), value(t, reg, "grpc_server_request_duration_seconds", map[string]string{"method": "/TestService/Get", "code": "NotFound"}))
+
+	errNotFound := errors.New("not found")
+	for _, repoErr := range []error{nil, errNotFound, errors.New("connection refused")} {
+		func() (err error) {
+			defer ObserveRepository("mysql", "get", time.Now(), &err, errNotFound)
+			return repoErr
+		}()
+	}
+	for _, result := range []string{ResultOK, ResultNotFound, ResultError} {
+		assert.Equal(t, float64(1), value(t, reg, "repository_operation_duration_seconds", map[string]string{"store": "mysql", "operation": "get", "result": result}))
+	}
+
+	registry := memory.NewRegistry()
+	assert.NoError(t, registry.Register(context.Background(), "rating-1", "rating", "localhost:8082"))
+	instrumented := InstrumentRegistry(registry)
+	_, err = instrumented.ServiceAddresses(context.Background(), "rating")
+	assert.NoError(t, err)
+	_, err = instrumented.ServiceAddresses(context.Background(), "metadata")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+	assert.Equal(t, float64(1), value(t, reg, "discovery_lookups_total", map[string]string{"target": "rating", "result": ResultOK}))
+	assert.Equal(t, float64(1), value(t, reg, "discovery_instances", map[string]string{"target": "rating"}))
+	assert.Equal(t, float64(1), value(t, reg, "discovery_lookups_total", map[string]string{"target": "metadata", "result": ResultNotFound}))
+
+	assert.NotZero(t, value(t, reg, "go_goroutines", nil))
+}
@@ -0,0 +1,372 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/internal/rest"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Paths of the specification and the documentation page, its assets are served under DocsPath + "/".
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+// Version is the version of the API described by generated documents.
+const Version = "v1"
+
+// Names of components which aren't generated from messages.
+const (
+	problemSchema = "Problem"
+	bearerScheme  = "bearerAuth"
+	apiKeyScheme  = "apiKey"
+)
+
+// Document defines an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info defines the API metadata.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem defines operations of a path keyed by lower case HTTP methods.
+type PathItem map[string]*Operation
+
+// Operation defines an API operation.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter defines a path or query parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody defines a request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response defines a response.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType defines the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema defines a JSON schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Components defines reusable schemas and security schemes.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme defines an authentication method.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Generate creates a document describing REST routes. Schemas are derived from the proto
+// messages of the gRPC methods the routes are mapped onto and follow their protojson encoding,
+// e.g. lowerCamelCase property names and int64 values encoded as strings.
+func Generate(title string, routes []rest.Route) *Document {
+	g := generator{schemas: map[string]*Schema{}}
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: Version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				apiKeyScheme: {Type: "apiKey", In: "header", Name: auth.APIKeyHeader},
+			},
+		},
+		Security: []map[string][]string{{bearerScheme: {}}, {apiKeyScheme: {}}},
+	}
+	g.schemas[problemSchema] = &Schema{
+		Type:        "object",
+		Description: "RFC 7807 problem details.",
+		Properties: map[string]*Schema{
+			"type":     {Type: "string"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer", Format: "int32"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string"},
+			"code":     {Type: "string", Description: "gRPC status code name, e.g. NOT_FOUND."},
+		},
+	}
+	for _, r := range routes {
+		item, ok := d.Paths[r.Pattern]
+		if !ok {
+			item = PathItem{}
+			d.Paths[r.Pattern] = item
+		}
+		item[strings.ToLower(r.Method)] = g.operation(r)
+	}
+	return d
+}
+
+// JSON returns the indented JSON encoding of the document.
+func (d *Document) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func (g generator) operation(r rest.Route) *Operation {
+	service, method, _ := strings.Cut(strings.TrimPrefix(r.RPC, "/"), "/")
+	op := &Operation{
+		OperationID: service + "_" + method,
+		Tags:        []string{service},
+		Responses: map[string]Response{
+			"default": {Description: "Error", Content: map[string]MediaType{
+				rest.ProblemContentType: {Schema: ref(problemSchema)},
+			}},
+		},
+	}
+	req := r.Call.Request()
+	bound := map[string]bool{}
+	for _, s := range strings.Split(strings.Trim(r.Pattern, "/"), "/") {
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			continue
+		}
+		name := s[1 : len(s)-1]
+		bound[name] = true
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: g.pathSchema(req, name)})
+	}
+	switch r.Body {
+	case "":
+		fields := req.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if bound[string(fd.Name())] || fd.Message() != nil || fd.IsList() {
+				continue
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: string(fd.Name()), In: "query", Schema: g.field(fd)})
+		}
+	case "*":
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.message(req))}
+	default:
+		if fd := req.Fields().ByName(protoreflect.Name(r.Body)); fd != nil && fd.Message() != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.message(fd.Message()))}
+		}
+	}
+
+	code := r.Status
+	if code == 0 {
+		code = http.StatusOK
+	}
+	resp := Response{Description: http.StatusText(code)}
+	if code != http.StatusNoContent {
+		md := r.Call.Response()
+		if fd := md.Fields().ByName(protoreflect.Name(r.ResponseBody)); r.ResponseBody != "" && fd != nil && fd.Message() != nil {
+			md = fd.Message()
+		}
+		resp.Content = jsonContent(g.message(md))
+		if r.Call.Streaming() {
+			// Messages are sent as data of server-sent events.
+			resp.Description = "Stream of server-sent events, each carrying a message as data"
+			resp.Content = map[string]MediaType{rest.EventStreamContentType: {Schema: g.message(md)}}
+		}
+	}
+	op.Responses[strconv.Itoa(code)] = resp
+	return op
+}
+
+// pathSchema returns the schema of a path parameter, dotted names refer to nested fields.
+func (g generator) pathSchema(md protoreflect.MessageDescriptor, path string) *Schema {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.Message() == nil {
+			return &Schema{Type: "string"}
+		}
+		md = fd.Message()
+	}
+	fd := md.Fields().ByName(protoreflect.Name(names[len(names)-1]))
+	if fd == nil {
+		return &Schema{Type: "string"}
+	}
+	return g.field(fd)
+}
+
+// message returns a reference to the schema of a message, adding it and schemas of nested messages
+// to components.
+func (g generator) message(md protoreflect.MessageDescriptor) *Schema {
+	if s, ok := wellKnown(md); ok {
+		return s
+	}
+	name := string(md.FullName())
+	if _, ok := g.schemas[name]; !ok {
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		// Added before its fields are, so that recursive messages terminate.
+		g.schemas[name] = s
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			s.Properties[fd.JSONName()] = g.field(fd)
+		}
+	}
+	return ref(name)
+}
+
+func (g generator) field(fd protoreflect.FieldDescriptor) *Schema {
+	switch {
+	case fd.IsMap():
+		return &Schema{Type: "object", AdditionalProperties: g.value(fd.MapValue())}
+	case fd.IsList():
+		return &Schema{Type: "array", Items: g.value(fd)}
+	}
+	return g.value(fd)
+}
+
+// value returns the schema of a single value of the field following protojson.
+func (g generator) value(fd protoreflect.FieldDescriptor) *Schema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "int64"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		s := &Schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.message(fd.Message())
+	}
+	return &Schema{Type: "string"}
+}
+
+// wellKnown returns schemas of well-known types which protojson encodes specially.
+func wellKnown(md protoreflect.MessageDescriptor) (*Schema, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration":
+		return &Schema{Type: "string", Description: "Duration in seconds with the s suffix, e.g. 1.5s."}, true
+	case "google.protobuf.FieldMask":
+		return &Schema{Type: "string"}, true
+	case "google.protobuf.Empty":
+		return &Schema{Type: "object"}, true
+	case "google.protobuf.Struct":
+		return &Schema{Type: "object", AdditionalProperties: &Schema{}}, true
+	case "google.protobuf.Value":
+		return &Schema{}, true
+	}
+	if md.ParentFile() != nil && md.ParentFile().Path() == "google/protobuf/wrappers.proto" {
+		return generator{}.value(md.Fields().ByName("value")), true
+	}
+	return nil, false
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// Handler returns the handler serving the document of the routes returned by routes,
+// so that routes added after it is created are included.
+func Handler(title string, routes func() []rest.Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, err := Generate(title, routes()).JSON()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	})
+}
+
+//go:embed docs.html
+var docs []byte
+
+// swaggerUI holds the assets of Swagger UI 4.15.5, see swaggerui/LICENSE.
+//
+//go:embed swaggerui/swagger-ui-bundle.js swaggerui/swagger-ui.css
+var swaggerUI embed.FS
+
+// DocsHandler returns the handler of a Swagger UI page rendering the document served at SpecPath
+// and of its assets, which are embedded so that the page doesn't depend on a CDN.
+func DocsHandler() http.Handler {
+	assets, _ := fs.Sub(swaggerUI, "swaggerui")
+	files := http.StripPrefix(DocsPath+"/", http.FileServer(http.FS(assets)))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case DocsPath:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(docs)
+		case DocsPath + "/":
+			http.NotFound(w, req)
+		default:
+			files.ServeHTTP(w, req)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/rest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSchemas fails if schemas of the API messages don't describe their protojson encoding.
+func TestSchemas(t *testing.T) {
+	messages := gen.File_movie_proto.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		t.Run(string(md.Name()), func(t *testing.T) {
+			g := generator{schemas: map[string]*Schema{}}
+			s := g.message(md)
+			b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(populate(md))
+			assert.NoError(t, err)
+			var v any
+			assert.NoError(t, json.Unmarshal(b, &v))
+			assert.NoError(t, validate(g.schemas, s, v, string(md.Name())))
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	h := gen.UnimplementedRatingServiceServer{}
+	d := Generate("rating", []rest.Route{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/v1/ratings/{record_type}",
+			RPC:     gen.RatingService_GetAggregatedRating_FullMethodName,
+			Call:    rest.Unary(h.GetAggregatedRating),
+		},
+		{
+			Method:  http.MethodPost,
+			Pattern: "/v1/ratings",
+			RPC:     gen.RatingService_PutRating_FullMethodName,
+			Call:    rest.Unary(h.PutRating),
+			Body:    "*",
+			Status:  http.StatusNoContent,
+		},
+	})
+
+	get := d.Paths["/v1/ratings/{record_type}"]["get"]
+	assert.Equal(t, "RatingService_GetAggregatedRating", get.OperationID)
+	assert.Equal(t, []Parameter{
+		{Name: "record_type", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "record_id", In: "query", Schema: &Schema{Type: "string"}},
+	}, get.Parameters)
+	assert.Equal(t, ref("GetAggregatedRatingResponse"), get.Responses["200"].Content["application/json"].Schema)
+
+	post := d.Paths["/v1/ratings"]["post"]
+	assert.Equal(t, ref("PutRatingRequest"), post.RequestBody.Content["application/json"].Schema)
+	assert.Empty(t, post.Responses["204"].Content)
+	assert.Equal(t, &Schema{Type: "integer", Format: "int32"}, d.Components.Schemas["PutRatingRequest"].Properties["ratingValue"])
+}
+
+// populate returns a message with all fields set, lists and maps have a single element.
+func TestDocsHandler(t *testing.T) {
+	tests := []struct {
+		path            string
+		wantStatus      int
+		wantContentType string
+	}{
+		{path: DocsPath, wantStatus: http.StatusOK, wantContentType: "text/html; charset=utf-8"},
+		{path: DocsPath + "/swagger-ui.css", wantStatus: http.StatusOK, wantContentType: "text/css; charset=utf-8"},
+		{path: DocsPath + "/swagger-ui-bundle.js", wantStatus: http.StatusOK, wantContentType: "text/javascript; charset=utf-8"},
+		{path: DocsPath + "/", wantStatus: http.StatusNotFound},
+		{path: DocsPath + "/LICENSE", wantStatus: http.StatusNotFound},
+	}
+	h := DocsHandler()
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			}
+			assert.NotContains(t, w.Body.String(), "https://unpkg.com", "assets are served locally")
+		})
+	}
+}
+
+func populate(md protoreflect.MessageDescriptor) protoreflect.ProtoMessage {
+	m := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		switch {
+		case fd.IsMap():
+			m.Mutable(fd).Map().Set(protoreflect.ValueOfString("key").MapKey(), value(fd.MapValue()))
+		case fd.IsList():
+			m.Mutable(fd).List().Append(value(fd))
+		case fd.Message() == nil || fd.Message().FullName() != md.FullName():
+			m.Set(fd, value(fd))
+		}
+	}
+	return m
+}
+
+func value(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		return protoreflect.ValueOfMessage(populate(fd.Message()).ProtoReflect())
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString("value")
+	case protoreflect.Int32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5)
+	}
+	panic(fmt.Sprintf("populate %s: unsupported kind %s", fd.FullName(), fd.Kind()))
+}
+
+// validate checks that the JSON value conforms to the schema and sets all of its properties.
+func validate(schemas map[string]*Schema, s *Schema, v any, path string) error {
+	if s.Ref != "" {
+		return validate(schemas, schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")], v, path)
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an object", path, v)
+		}
+		if s.AdditionalProperties != nil {
+			for name, value := range obj {
+				if err := validate(schemas, s.AdditionalProperties, value, path+"."+name); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for name := range obj {
+			if _, ok := s.Properties[name]; !ok {
+				return fmt.Errorf("%s: property %s isn't described", path, name)
+			}
+		}
+		for name, p := range s.Properties {
+			value, ok := obj[name]
+			if !ok {
+				return fmt.Errorf("%s: property %s is missing", path, name)
+			}
+			if err := validate(schemas, p, value, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an array", path, v)
+		}
+		for i, item := range items {
+			if err := validate(schemas, s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: %T is not a string", path, v)
+		}
+	case "integer", "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: %T is not a number", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %T is not a boolean", path, v)
+		}
+	}
+	return nil
+}
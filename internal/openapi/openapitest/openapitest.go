@@ -0,0 +1,27 @@
+// Package openapitest provides a test of OpenAPI documents checked in next to the code of the routes they describe.
+package openapitest
+
+import (
+	"flag"
+	"github.com/mkvy/movies-app/internal/openapi"
+	"github.com/mkvy/movies-app/internal/rest"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the OpenAPI document")
+
+// AssertDocument fails if the document at path differs from the one generated from the routes.
+// The document is regenerated when the test runs with -update.
+func AssertDocument(t *testing.T, title string, routes []rest.Route, path string) {
+	t.Helper()
+	got, err := openapi.Generate(title, routes).JSON()
+	assert.NoError(t, err)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "the OpenAPI document is out of date, run the test with -update")
+}
@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Broker notifies subscribers of changes of keys, e.g. record IDs, within a process.
+// Notifications carry no values and are coalesced: subscribers read the current state once notified,
+// so slow subscribers skip intermediate changes instead of blocking publishers.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+// New creates a new broker.
+func New() *Broker {
+	return &Broker{subs: map[string]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel receiving a value after changes of the key and a function cancelling the subscription.
+func (b *Broker) Subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[key] == nil {
+		b.subs[key] = map[chan struct{}]struct{}{}
+	}
+	b.subs[key][ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[key], ch)
+		if len(b.subs[key]) == 0 {
+			delete(b.subs, key)
+		}
+	}
+}
+
+// Publish notifies subscribers of the key of a change.
+func (b *Broker) Publish(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watch calls poll and then again after each change of the key and each interval, until ctx is done or poll fails.
+// Polling picks up changes made by other instances, which aren't published by this broker.
+func (b *Broker) Watch(ctx context.Context, key string, interval time.Duration, poll func(ctx context.Context) error) error {
+	ch, cancel := b.Subscribe(key)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := poll(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		case <-ticker.C:
+		}
+	}
+}
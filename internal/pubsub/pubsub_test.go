@@ -0,0 +1,67 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPublish(t *testing.T) {
+	b := New()
+	ch, cancel := b.Subscribe("1")
+	other, cancelOther := b.Subscribe("2")
+	defer cancelOther()
+
+	b.Publish("1")
+	b.Publish("1")
+	assert.Len(t, ch, 1, "notifications are coalesced")
+	assert.Len(t, other, 0)
+	<-ch
+
+	cancel()
+	b.Publish("1")
+	assert.Len(t, ch, 0)
+	assert.Empty(t, b.subs["1"])
+}
+
+func TestWatch(t *testing.T) {
+	errDone := errors.New("done")
+	tests := []struct {
+		name     string
+		interval time.Duration
+		publish  bool
+	}{
+		{name: "published", interval: time.Hour, publish: true},
+		{name: "polled", interval: time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			polls := make(chan struct{})
+			errc := make(chan error, 1)
+			n := 0
+			go func() {
+				errc <- b.Watch(context.Background(), "1", tt.interval, func(context.Context) error {
+					n++
+					if n == 2 {
+						return errDone
+					}
+					polls <- struct{}{}
+					return nil
+				})
+			}()
+			<-polls
+			if tt.publish {
+				b.Publish("1")
+			}
+			assert.ErrorIs(t, <-errc, errDone)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := New().Watch(ctx, "1", time.Hour, func(context.Context) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,242 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AnyMethod is the method key of limits applied to each method without its own limits.
+const AnyMethod = "*"
+
+// RetryAfterHeader is the metadata key carrying the number of seconds to wait before retrying a rejected request.
+const RetryAfterHeader = "retry-after"
+
+// DefaultMaxCallers is the default number of callers tracked at once.
+const DefaultMaxCallers = 10000
+
+// Limit defines a token bucket refilled at Rate tokens per second holding up to Burst tokens.
+type Limit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// Config defines rate limiting configuration. Limits are keyed by full gRPC method names,
+// e.g. /MovieService/GetMovieDetails, or AnyMethod.
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Methods limits the total rate of requests to a method.
+	Methods map[string]Limit `yaml:"methods"`
+	// Callers limits the rate of requests to a method by a single caller.
+	Callers map[string]Limit `yaml:"callers"`
+	// MaxCallers bounds the number of callers tracked at once, least recently seen callers are forgotten.
+	MaxCallers int `yaml:"maxCallers"`
+}
+
+// Validate checks the configuration consistency.
+func (c Config) Validate() error {
+	for kind, limits := range map[string]map[string]Limit{"methods": c.Methods, "callers": c.Callers} {
+		for method, l := range limits {
+			if l.Rate <= 0 || l.Burst <= 0 {
+				return fmt.Errorf("%s %s: rate and burst must be positive", kind, method)
+			}
+		}
+	}
+	if c.MaxCallers < 0 {
+		return fmt.Errorf("maxCallers must not be negative")
+	}
+	return nil
+}
+
+// CallerFunc returns the identity of the caller of a request.
+type CallerFunc func(ctx context.Context) string
+
+// PeerCaller identifies callers by the authenticated principal or, for unauthenticated requests,
+// by the peer IP address. Credentials aren't used before they are verified, so that callers
+// can't get fresh buckets by sending made up ones.
+func PeerCaller(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return "principal:" + p.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return ""
+}
+
+// Limiter defines a gRPC request rate limiter with token buckets per method and per caller.
+type Limiter struct {
+	caller CallerFunc
+
+	mu      sync.Mutex
+	cfg     Config
+	methods map[string]*rate.Limiter
+	callers map[callerKey]*list.Element
+	lru     *list.List
+}
+
+type callerKey struct {
+	method string
+	caller string
+}
+
+type callerBucket struct {
+	key     callerKey
+	limiter *rate.Limiter
+}
+
+// New creates a new rate limiter. Caller is optional, PeerCaller is used by default.
+func New(cfg Config, caller CallerFunc) *Limiter {
+	if caller == nil {
+		caller = PeerCaller
+	}
+	l := &Limiter{caller: caller}
+	l.Update(cfg)
+	return l
+}
+
+// Update replaces the limits at runtime. Buckets are refilled.
+func (l *Limiter) Update(cfg Config) {
+	if cfg.MaxCallers == 0 {
+		cfg.MaxCallers = DefaultMaxCallers
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	l.methods = map[string]*rate.Limiter{}
+	l.callers = map[callerKey]*list.Element{}
+	l.lru = list.New()
+}
+
+// UnaryServerInterceptor returns an interceptor rejecting requests over the limits
+// with ResourceExhausted and retry-after metadata.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if ok, retryAfter := l.Allow(ctx, info.FullMethod); !ok {
+			_ = grpc.SetHeader(ctx, retryAfterHeader(retryAfter))
+			return nil, rejection(info.FullMethod, retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream interceptor rejecting streams over the limits
+// like UnaryServerInterceptor. Each stream counts as a single request.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if ok, retryAfter := l.Allow(ss.Context(), info.FullMethod); !ok {
+			_ = ss.SetHeader(retryAfterHeader(retryAfter))
+			return rejection(info.FullMethod, retryAfter)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Allow reports whether a request to the method is within the limits, otherwise it returns
+// the time after which the request may be retried. Rejected requests don't consume tokens.
+func (l *Limiter) Allow(ctx context.Context, method string) (bool, time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	if !l.cfg.Enabled {
+		l.mu.Unlock()
+		return true, 0
+	}
+	var limiters []*rate.Limiter
+	if m := l.methodLimiter(method); m != nil {
+		limiters = append(limiters, m)
+	}
+	if c := l.callerLimiter(method, l.caller(ctx)); c != nil {
+		limiters = append(limiters, c)
+	}
+	l.mu.Unlock()
+
+	var reservations []*rate.Reservation
+	var wait time.Duration
+	for _, lim := range limiters {
+		r := lim.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > wait {
+			wait = d
+		}
+	}
+	if wait == 0 {
+		return true, 0
+	}
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+	return false, wait
+}
+
+// methodLimiter returns the bucket shared by all callers of the method, l.mu must be held.
+func (l *Limiter) methodLimiter(method string) *rate.Limiter {
+	limit, ok := lookup(l.cfg.Methods, method)
+	if !ok {
+		return nil
+	}
+	lim, ok := l.methods[method]
+	if !ok {
+		lim = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		l.methods[method] = lim
+	}
+	return lim
+}
+
+// callerLimiter returns the bucket of the caller for the method, l.mu must be held.
+func (l *Limiter) callerLimiter(method string, caller string) *rate.Limiter {
+	limit, ok := lookup(l.cfg.Callers, method)
+	if !ok || caller == "" {
+		return nil
+	}
+	k := callerKey{method, caller}
+	if el, ok := l.callers[k]; ok {
+		l.lru.MoveToFront(el)
+		return el.Value.(*callerBucket).limiter
+	}
+	b := &callerBucket{k, rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+	l.callers[k] = l.lru.PushFront(b)
+	for l.lru.Len() > l.cfg.MaxCallers {
+		el := l.lru.Back()
+		l.lru.Remove(el)
+		delete(l.callers, el.Value.(*callerBucket).key)
+	}
+	return b.limiter
+}
+
+// lookup returns the limit of the method falling back to AnyMethod.
+func lookup(limits map[string]Limit, method string) (Limit, bool) {
+	if limit, ok := limits[method]; ok {
+		return limit, true
+	}
+	limit, ok := limits[AnyMethod]
+	return limit, ok
+}
+
+func retryAfterHeader(retryAfter time.Duration) metadata.MD {
+	return metadata.Pairs(RetryAfterHeader, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+func rejection(method string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded for "+method)
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
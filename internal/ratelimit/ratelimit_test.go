@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func callerContext(subject string) context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{Subject: subject})
+}
+
+func TestPeerCaller(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}
+	withPeer := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "principal", ctx: auth.NewContext(withPeer, auth.Principal{Subject: "alice"}), want: "principal:alice"},
+		{name: "peer", ctx: withPeer, want: "ip:10.0.0.1"},
+		{
+			name: "unverified api key",
+			ctx:  metadata.NewIncomingContext(withPeer, metadata.Pairs(auth.APIKeyHeader, "made-up")),
+			want: "ip:10.0.0.1",
+		},
+		{name: "unknown", ctx: context.Background(), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PeerCaller(tt.ctx))
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		calls   []string
+		allowed []bool
+	}{
+		{
+			name:    "disabled",
+			cfg:     Config{Methods: map[string]Limit{AnyMethod: {Rate: 0.001, Burst: 1}}},
+			calls:   []string{"a", "a"},
+			allowed: []bool{true, true},
+		},
+		{
+			name:    "method limit shared by callers",
+			cfg:     Config{Enabled: true, Methods: map[string]Limit{"/Get": {Rate: 0.001, Burst: 2}}},
+			calls:   []string{"a", "b", "c"},
+			allowed: []bool{true, true, false},
+		},
+		{
+			name:    "caller limit",
+			cfg:     Config{Enabled: true, Callers: map[string]Limit{AnyMethod: {Rate: 0.001, Burst: 1}}},
+			calls:   []string{"a", "b", "a"},
+			allowed: []bool{true, true, false},
+		},
+		{
+			name: "rejected requests don't consume tokens",
+			cfg: Config{
+				Enabled: true,
+				Methods: map[string]Limit{"/Get": {Rate: 0.001, Burst: 2}},
+				Callers: map[string]Limit{"/Get": {Rate: 0.001, Burst: 1}},
+			},
+			calls:   []string{"a", "a", "b", "c"},
+			allowed: []bool{true, false, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.cfg, nil)
+			for i, caller := range tt.calls {
+				ok, retryAfter := l.Allow(callerContext(caller), "/Get")
+				assert.Equal(t, tt.allowed[i], ok, "call %d", i)
+				assert.Equal(t, !ok, retryAfter > 0, "call %d", i)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l := New(Config{Enabled: true, Methods: map[string]Limit{AnyMethod: {Rate: 1, Burst: 1}}}, nil)
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/Get"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	res, err := interceptor(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		assert.Greater(t, st.Details()[0].(*errdetails.RetryInfo).RetryDelay.AsDuration().Seconds(), 0.5)
+	}
+
+	l.Update(Config{})
+	_, err = interceptor(context.Background(), nil, info, handler)
+	assert.NoError(t, err, "limits are updated")
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	header metadata.MD
+}
+
+func (s *serverStream) Context() context.Context { return context.Background() }
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l := New(Config{Enabled: true, Methods: map[string]Limit{AnyMethod: {Rate: 1, Burst: 1}}}, nil)
+	interceptor := l.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/Watch"}
+	calls := 0
+	handler := func(srv any, ss grpc.ServerStream) error {
+		calls++
+		return nil
+	}
+
+	ss := &serverStream{}
+	assert.NoError(t, interceptor(nil, ss, info, handler))
+
+	err := interceptor(nil, ss, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"1"}, ss.header.Get(RetryAfterHeader))
+	assert.Equal(t, 1, calls)
+}
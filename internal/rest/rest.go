@@ -0,0 +1,480 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of error responses, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// maxBodySize bounds the size of request bodies.
+const maxBodySize = 1 << 20
+
+// forwardedHeaders lists HTTP headers passed to gRPC interceptors as incoming metadata.
+var forwardedHeaders = []string{auth.AuthorizationHeader, auth.APIKeyHeader, logging.RequestIDHeader}
+
+// Invoker calls a unary gRPC method handler through server interceptors.
+type Invoker func(ctx context.Context, fullMethod string, req any, handler grpc.UnaryHandler) (any, error)
+
+// StreamInvoker calls a streaming gRPC method handler through server interceptors.
+type StreamInvoker func(fullMethod string, stream grpc.ServerStream, handler grpc.StreamHandler) error
+
+// Call defines a unary or server-streaming gRPC method implementation, see Unary and ServerStream.
+type Call struct {
+	newRequest func() proto.Message
+	handler    grpc.UnaryHandler
+	stream     func(req proto.Message, stream grpc.ServerStream) error
+	request    protoreflect.MessageDescriptor
+	response   protoreflect.MessageDescriptor
+}
+
+// Streaming reports whether the call is of a server-streaming method.
+func (c Call) Streaming() bool {
+	return c.stream != nil
+}
+
+// Request returns the descriptor of the request message.
+func (c Call) Request() protoreflect.MessageDescriptor {
+	return c.request
+}
+
+// Response returns the descriptor of the response message.
+func (c Call) Response() protoreflect.MessageDescriptor {
+	return c.response
+}
+
+// Unary returns a call of a unary method of a gRPC service implementation, e.g. Unary(h.GetMetadata).
+func Unary[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](fn func(ctx context.Context, req PReq) (Resp, error)) Call {
+	var resp Resp
+	return Call{
+		request:    PReq(new(Req)).ProtoReflect().Descriptor(),
+		response:   resp.ProtoReflect().Descriptor(),
+		newRequest: func() proto.Message { return PReq(new(Req)) },
+		handler: func(ctx context.Context, req any) (any, error) {
+			return fn(ctx, req.(PReq))
+		},
+	}
+}
+
+// ServerStream returns a call of a server-streaming method of a gRPC service implementation,
+// e.g. ServerStream[*gen.WatchMovieDetailsResponse](h.WatchMovieDetails).
+func ServerStream[Resp proto.Message, Req any, PReq interface {
+	*Req
+	proto.Message
+}, S interface {
+	Send(Resp) error
+	grpc.ServerStream
+}](fn func(req PReq, stream S) error) Call {
+	var resp Resp
+	return Call{
+		request:    PReq(new(Req)).ProtoReflect().Descriptor(),
+		response:   resp.ProtoReflect().Descriptor(),
+		newRequest: func() proto.Message { return PReq(new(Req)) },
+		stream: func(req proto.Message, stream grpc.ServerStream) error {
+			return fn(req.(PReq), any(sender[Resp]{stream}).(S))
+		},
+	}
+}
+
+// sender implements generated server stream interfaces.
+type sender[Resp proto.Message] struct {
+	grpc.ServerStream
+}
+
+func (s sender[Resp]) Send(m Resp) error {
+	return s.SendMsg(m)
+}
+
+// Route maps an HTTP route onto a unary or server-streaming gRPC method. Path parameters, e.g. {movie_id},
+// and query parameters of requests without a body are set to request fields of the same proto names,
+// dotted names refer to nested fields, e.g. {metadata.id}. Bodies are protojson encoded.
+// Responses of streaming methods are sent as server-sent events, see eventStream.
+type Route struct {
+	// Method is the HTTP request method.
+	Method string
+	// Pattern is the request path, e.g. /v1/movies/{movie_id}.
+	Pattern string
+	// RPC is the full name of the gRPC method, e.g. /MovieService/GetMovieDetails.
+	// Requests are authenticated, authorized and rate limited like calls of the method.
+	RPC  string
+	Call Call
+	// Body is the request field the body is decoded into, * for the whole request or empty if there is none.
+	Body string
+	// ResponseBody is the response field returned as the body, the whole response is returned if empty.
+	ResponseBody string
+	// Status is the status code of successful responses, 200 if zero. Bodies aren't written with 204.
+	Status int
+
+	segments []string
+}
+
+// Gateway serves REST routes by calling gRPC method implementations.
+type Gateway struct {
+	invoke       Invoker
+	invokeStream StreamInvoker
+	routes       []Route
+}
+
+// New creates a new gateway calling unary methods with invoke and streaming methods with invokeStream,
+// methods are called directly if they are nil.
+func New(invoke Invoker, invokeStream StreamInvoker) *Gateway {
+	if invoke == nil {
+		invoke = func(ctx context.Context, _ string, req any, handler grpc.UnaryHandler) (any, error) {
+			return handler(ctx, req)
+		}
+	}
+	if invokeStream == nil {
+		invokeStream = func(_ string, stream grpc.ServerStream, handler grpc.StreamHandler) error {
+			return handler(nil, stream)
+		}
+	}
+	return &Gateway{invoke: invoke, invokeStream: invokeStream}
+}
+
+// Handle adds routes. It panics on invalid patterns or duplicate routes, like http.ServeMux.
+func (g *Gateway) Handle(routes ...Route) {
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Pattern, "/") || r.Method == "" || r.RPC == "" || r.Call.handler == nil && r.Call.stream == nil {
+			panic(fmt.Sprintf("rest: invalid route %s %s", r.Method, r.Pattern))
+		}
+		r.segments = strings.Split(strings.Trim(r.Pattern, "/"), "/")
+		for _, existing := range g.routes {
+			if existing.Method == r.Method && existing.Pattern == r.Pattern {
+				panic(fmt.Sprintf("rest: duplicate route %s %s", r.Method, r.Pattern))
+			}
+		}
+		g.routes = append(g.routes, r)
+	}
+}
+
+// Routes returns the routes served by the gateway.
+func (g *Gateway) Routes() []Route {
+	return append([]Route(nil), g.routes...)
+}
+
+// Match reports whether the request path matches any of the routes.
+func (g *Gateway) Match(req *http.Request) bool {
+	for _, r := range g.routes {
+		if _, ok := r.match(req.URL.Path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var allowed []string
+	for _, r := range g.routes {
+		params, ok := r.match(req.URL.Path)
+		if !ok {
+			continue
+		}
+		if r.Method != req.Method {
+			allowed = append(allowed, r.Method)
+			continue
+		}
+		g.serve(w, req, r, params)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		WriteProblem(w, req, http.StatusMethodNotAllowed, "method "+req.Method+" is not allowed")
+		return
+	}
+	WriteProblem(w, req, http.StatusNotFound, "no route for "+req.URL.Path)
+}
+
+func (r Route) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, s := range r.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[s[1:len(s)-1]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (g *Gateway) serve(w http.ResponseWriter, req *http.Request, r Route, params map[string]string) {
+	ctx := req.Context()
+	msg := r.Call.newRequest()
+	if err := decode(req, r, msg, params); err != nil {
+		WriteError(w, req, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	if r.Call.stream != nil {
+		stream := newEventStream(w, req, r.ResponseBody)
+		stream.close(g.invokeStream(r.RPC, stream, func(_ any, ss grpc.ServerStream) error {
+			return r.Call.stream(msg, ss)
+		}))
+		return
+	}
+	resp, err := g.invoke(incomingContext(ctx, req), r.RPC, msg, r.Call.handler)
+	if err != nil {
+		WriteError(w, req, err)
+		return
+	}
+	out := responseBody(resp.(proto.Message), r.ResponseBody)
+	code := r.Status
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(out)
+	if err != nil {
+		WriteError(w, req, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		logging.FromContext(ctx).Debug("Failed to write response", zap.Error(err))
+	}
+}
+
+// responseBody returns the field of the response returned as the body, the whole response if it is empty.
+func responseBody(resp proto.Message, field string) proto.Message {
+	if field == "" {
+		return resp
+	}
+	m := resp.ProtoReflect()
+	if fd := m.Descriptor().Fields().ByName(protoreflect.Name(field)); fd != nil && fd.Message() != nil {
+		return m.Get(fd).Message().Interface()
+	}
+	return resp
+}
+
+// decode builds the request message of the body, path and query parameters.
+// Path parameters must not contradict the body.
+func decode(req *http.Request, r Route, msg proto.Message, params map[string]string) error {
+	if r.Body != "" {
+		b, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, maxBodySize))
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+		target := msg
+		if r.Body != "*" {
+			m := msg.ProtoReflect()
+			fd := m.Descriptor().Fields().ByName(protoreflect.Name(r.Body))
+			if fd == nil || fd.Message() == nil {
+				return fmt.Errorf("unknown body field %s", r.Body)
+			}
+			target = m.Mutable(fd).Message().Interface()
+		}
+		if err := protojson.Unmarshal(b, target); err != nil {
+			return fmt.Errorf("invalid body: %w", err)
+		}
+	} else {
+		for name, values := range req.URL.Query() {
+			if err := setField(msg.ProtoReflect(), name, values[len(values)-1], false); err != nil {
+				return err
+			}
+		}
+	}
+	for name, value := range params {
+		if err := setField(msg.ProtoReflect(), name, value, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField sets a scalar field by a dotted proto name. With strict set, a value already set
+// to something else is an error.
+func setField(m protoreflect.Message, path string, value string, strict bool) error {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("unknown parameter %s", path)
+		}
+		m = m.Mutable(fd).Message()
+	}
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(names[len(names)-1]))
+	if fd == nil || fd.IsList() || fd.IsMap() {
+		return fmt.Errorf("unknown parameter %s", path)
+	}
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(value)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", path, err)
+		}
+		v = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", path, err)
+		}
+		v = protoreflect.ValueOfInt64(i)
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", path, err)
+		}
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", path, err)
+		}
+		v = protoreflect.ValueOfBool(b)
+	default:
+		return fmt.Errorf("parameter %s of unsupported type %s", path, fd.Kind())
+	}
+	if strict && m.Has(fd) && !m.Get(fd).Equal(v) {
+		return fmt.Errorf("%s of the body doesn't match the path", path)
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// incomingContext passes credentials and the request ID to gRPC interceptors as incoming metadata
+// and the client address as the peer.
+func incomingContext(ctx context.Context, req *http.Request) context.Context {
+	md := metadata.MD{}
+	for _, h := range forwardedHeaders {
+		if v := req.Header.Get(h); v != "" {
+			md.Set(h, v)
+		}
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: remoteAddr(req.RemoteAddr)})
+}
+
+type remoteAddr string
+
+func (a remoteAddr) Network() string { return "tcp" }
+
+func (a remoteAddr) String() string { return string(a) }
+
+// HTTPStatus returns the HTTP status code corresponding to a gRPC status code.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem defines an RFC 7807 problem details body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the gRPC status code name, e.g. NOT_FOUND.
+	Code string `json:"code,omitempty"`
+}
+
+// WriteError writes a problem+json response of an error, gRPC status errors are mapped with HTTPStatus.
+// Retry-After is set for errors carrying retry info, e.g. rate limit rejections.
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	st := status.Convert(err)
+	code := HTTPStatus(st.Code())
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(info.RetryDelay.AsDuration().Seconds()))))
+		}
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="movies-app"`)
+	}
+	if code >= http.StatusInternalServerError {
+		logging.FromContext(req.Context()).Error("Request failed", zap.Error(err))
+	}
+	writeProblem(w, req, code, st.Message(), CodeName(st.Code()))
+}
+
+// WriteProblem writes a problem+json response.
+func WriteProblem(w http.ResponseWriter, req *http.Request, code int, detail string) {
+	writeProblem(w, req, code, detail, "")
+}
+
+func writeProblem(w http.ResponseWriter, req *http.Request, code int, detail string, grpcCode string) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(newProblem(req, code, detail, grpcCode))
+}
+
+func newProblem(req *http.Request, code int, detail string, grpcCode string) Problem {
+	title := http.StatusText(code)
+	if title == "" {
+		title = "Client Closed Request"
+	}
+	return Problem{Type: "about:blank", Title: title, Status: code, Detail: detail, Instance: req.URL.Path, Code: grpcCode}
+}
+
+// CodeName returns the canonical name of a gRPC code, e.g. NOT_FOUND.
+func CodeName(c codes.Code) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range c.String() {
+		if r >= 'A' && r <= 'Z' && prev >= 'a' && prev <= 'z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return strings.ToUpper(b.String())
+}
@@ -0,0 +1,269 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type metadataServer struct {
+	gen.UnimplementedMetadataServiceServer
+	put *gen.Metadata
+}
+
+func (s *metadataServer) GetMetadata(ctx context.Context, req *gen.GetMetadataRequest) (*gen.GetMetadataResponse, error) {
+	switch req.MovieId {
+	case "the-movie":
+		return &gen.GetMetadataResponse{Metadata: &gen.Metadata{Id: req.MovieId, Title: "The Movie"}}, nil
+	case "limited":
+		st, _ := status.New(codes.ResourceExhausted, "rate limit exceeded").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+		return nil, st.Err()
+	}
+	return nil, status.Error(codes.NotFound, "not found")
+}
+
+func (s *metadataServer) PutMetadata(ctx context.Context, req *gen.PutMetadataRequest) (*gen.PutMetadataResponse, error) {
+	s.put = req.Metadata
+	return &gen.PutMetadataResponse{}, nil
+}
+
+func (s *metadataServer) WatchMetadata(req *gen.WatchMetadataRequest, stream gen.MetadataService_WatchMetadataServer) error {
+	if req.MovieId != "the-movie" {
+		return status.Error(codes.NotFound, "not found")
+	}
+	for _, title := range []string{"The Movie", "The Movie 2"} {
+		if err := stream.Send(&gen.WatchMetadataResponse{Metadata: &gen.Metadata{Id: req.MovieId, Title: title}}); err != nil {
+			return err
+		}
+	}
+	return status.Error(codes.Unavailable, "shutting down")
+}
+
+func newGateway(srv *metadataServer, invoke Invoker, invokeStream StreamInvoker) *Gateway {
+	g := New(invoke, invokeStream)
+	g.Handle(
+		Route{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/metadata/{movie_id}",
+			RPC:          gen.MetadataService_GetMetadata_FullMethodName,
+			Call:         Unary(srv.GetMetadata),
+			ResponseBody: "metadata",
+		},
+		Route{
+			Method:  http.MethodPut,
+			Pattern: "/v1/metadata/{metadata.id}",
+			RPC:     gen.MetadataService_PutMetadata_FullMethodName,
+			Call:    Unary(srv.PutMetadata),
+			Body:    "metadata",
+			Status:  http.StatusNoContent,
+		},
+		Route{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/metadata/{movie_id}/watch",
+			RPC:          gen.MetadataService_WatchMetadata_FullMethodName,
+			Call:         ServerStream[*gen.WatchMetadataResponse](srv.WatchMetadata),
+			ResponseBody: "metadata",
+		},
+	)
+	return g
+}
+
+func TestGateway(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantCode   int
+		wantBody   string
+		wantHeader map[string]string
+		wantPut    *gen.Metadata
+	}{
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			path:     "/v1/metadata/the-movie",
+			wantCode: http.StatusOK,
+			wantBody: `{"id":"the-movie","title":"The Movie","description":"","director":""}`,
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/v1/metadata/missing",
+			wantCode:   http.StatusNotFound,
+			wantBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","instance":"/v1/metadata/missing","code":"NOT_FOUND"}`,
+			wantHeader: map[string]string{"Content-Type": ProblemContentType},
+		},
+		{
+			name:       "rate limited",
+			method:     http.MethodGet,
+			path:       "/v1/metadata/limited",
+			wantCode:   http.StatusTooManyRequests,
+			wantHeader: map[string]string{"Retry-After": "2"},
+		},
+		{
+			name:     "put",
+			method:   http.MethodPut,
+			path:     "/v1/metadata/the-movie",
+			body:     `{"title":"The Movie","director":"Mr. D"}`,
+			wantCode: http.StatusNoContent,
+			wantPut:  &gen.Metadata{Id: "the-movie", Title: "The Movie", Director: "Mr. D"},
+		},
+		{
+			name:     "put with mismatching id",
+			method:   http.MethodPut,
+			path:     "/v1/metadata/the-movie",
+			body:     `{"id":"another-movie"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "put with unknown field",
+			method:   http.MethodPut,
+			path:     "/v1/metadata/the-movie",
+			body:     `{"rating":5}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodDelete,
+			path:       "/v1/metadata/the-movie",
+			wantCode:   http.StatusMethodNotAllowed,
+			wantHeader: map[string]string{"Allow": "GET, PUT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &metadataServer{}
+			w := httptest.NewRecorder()
+			newGateway(srv, nil, nil).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+			for k, v := range tt.wantHeader {
+				assert.Equal(t, v, w.Header().Get(k), k)
+			}
+			if tt.wantPut != nil {
+				assert.Equal(t, tt.wantPut.String(), srv.put.String())
+			}
+		})
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	var gotMethod string
+	var gotMD metadata.MD
+	g := newGateway(&metadataServer{}, func(ctx context.Context, method string, req any, handler grpc.UnaryHandler) (any, error) {
+		gotMethod = method
+		gotMD, _ = metadata.FromIncomingContext(ctx)
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/metadata/the-movie", nil)
+	req.Header.Set("X-Api-Key", "key")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+
+	assert.Equal(t, gen.MetadataService_GetMetadata_FullMethodName, gotMethod)
+	assert.Equal(t, []string{"key"}, gotMD.Get("x-api-key"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	var p Problem
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, "UNAUTHENTICATED", p.Code)
+}
+
+type event struct {
+	name string
+	data string
+}
+
+// parseEvents splits a server-sent events body into events with their names and data.
+func parseEvents(t *testing.T, body string) []event {
+	assert.True(t, strings.HasSuffix(body, "\n\n"), "events end with a blank line")
+	var res []event
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		var e event
+		if rest, ok := strings.CutPrefix(block, "event: "); ok {
+			e.name, block, _ = strings.Cut(rest, "\n")
+		}
+		data, ok := strings.CutPrefix(block, "data: ")
+		assert.True(t, ok, "event %q has data", block)
+		e.data = data
+		res = append(res, e)
+	}
+	return res
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		wantCode        int
+		wantContentType string
+		wantEvents      []event
+		wantBody        string
+	}{
+		{
+			name:            "events",
+			path:            "/v1/metadata/the-movie/watch",
+			wantCode:        http.StatusOK,
+			wantContentType: EventStreamContentType,
+			wantEvents: []event{
+				{data: `{"id":"the-movie","title":"The Movie","description":"","director":""}`},
+				{data: `{"id":"the-movie","title":"The Movie 2","description":"","director":""}`},
+				{name: "error", data: `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"shutting down","instance":"/v1/metadata/the-movie/watch","code":"UNAVAILABLE"}`},
+			},
+		},
+		{
+			name:            "error before the first message",
+			path:            "/v1/metadata/missing/watch",
+			wantCode:        http.StatusNotFound,
+			wantContentType: ProblemContentType,
+			wantBody:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","instance":"/v1/metadata/missing/watch","code":"NOT_FOUND"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod string
+			var gotMD metadata.MD
+			g := newGateway(&metadataServer{}, nil, func(method string, stream grpc.ServerStream, handler grpc.StreamHandler) error {
+				gotMethod = method
+				gotMD, _ = metadata.FromIncomingContext(stream.Context())
+				return handler(nil, stream)
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Api-Key", "key")
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+
+			assert.Equal(t, gen.MetadataService_WatchMetadata_FullMethodName, gotMethod)
+			assert.Equal(t, []string{"key"}, gotMD.Get("x-api-key"))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			if tt.wantEvents == nil {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+				return
+			}
+			events := parseEvents(t, w.Body.String())
+			if assert.Len(t, events, len(tt.wantEvents)) {
+				for i, want := range tt.wantEvents {
+					assert.Equal(t, want.name, events[i].name, "event %d", i)
+					assert.JSONEq(t, want.data, events[i].data, "event %d", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventStreamContentType is the media type of responses of streaming methods.
+const EventStreamContentType = "text/event-stream"
+
+// keepAliveInterval is the interval of comments sent on idle event streams, so that proxies don't close them.
+const keepAliveInterval = 15 * time.Second
+
+// eventStream implements grpc.ServerStream writing messages as server-sent events. Each message is
+// sent as a message event with protojson data. Errors before the first message are written like
+// errors of unary methods, later ones as an error event with problem details as data.
+type eventStream struct {
+	w            http.ResponseWriter
+	req          *http.Request
+	ctx          context.Context
+	responseBody string
+
+	mu      sync.Mutex
+	started bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newEventStream(w http.ResponseWriter, req *http.Request, responseBody string) *eventStream {
+	s := &eventStream{
+		w:            w,
+		req:          req,
+		ctx:          incomingContext(req.Context(), req),
+		responseBody: responseBody,
+		done:         make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.keepAlive()
+	return s
+}
+
+func (s *eventStream) SetHeader(metadata.MD) error { return nil }
+
+func (s *eventStream) SendHeader(metadata.MD) error { return nil }
+
+func (s *eventStream) SetTrailer(metadata.MD) {}
+
+func (s *eventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *eventStream) SendMsg(m any) error {
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(responseBody(m.(proto.Message), s.responseBody))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		h := s.w.Header()
+		h.Set("Content-Type", EventStreamContentType)
+		h.Set("Cache-Control", "no-cache")
+		// Disables response buffering of nginx.
+		h.Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	return s.writeLocked("data: " + string(b) + "\n\n")
+}
+
+// RecvMsg returns io.EOF, the request is passed to the method directly.
+func (s *eventStream) RecvMsg(any) error {
+	return io.EOF
+}
+
+func (s *eventStream) keepAlive() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.started {
+				_ = s.writeLocked(": keepalive\n\n")
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *eventStream) writeLocked(event string) error {
+	if _, err := io.WriteString(s.w, event); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// close ends the stream with the error returned by the method.
+func (s *eventStream) close(err error) {
+	close(s.done)
+	s.wg.Wait()
+	if err == nil || s.req.Context().Err() != nil {
+		return
+	}
+	if !s.started {
+		WriteError(s.w, s.req, err)
+		return
+	}
+	st := status.Convert(err)
+	code := HTTPStatus(st.Code())
+	if code >= http.StatusInternalServerError {
+		logging.FromContext(s.req.Context()).Error("Stream failed", zap.Error(err))
+	}
+	b, _ := json.Marshal(newProblem(s.req, code, st.Message(), CodeName(st.Code())))
+	if err := s.writeLocked("event: error\ndata: " + string(b) + "\n\n"); err != nil {
+		logging.FromContext(s.req.Context()).Debug("Failed to write error event", zap.Error(err))
+	}
+}
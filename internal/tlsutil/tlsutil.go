@@ -0,0 +1,235 @@
+package tlsutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transport security modes.
+const (
+	// ModeInsecure disables transport security, it is meant for development only.
+	ModeInsecure = "insecure"
+	// ModeTLS enables server TLS, clients verify the server certificate.
+	ModeTLS = "tls"
+	// ModeMTLS enables mutual TLS, servers also verify client certificates.
+	ModeMTLS = "mtls"
+)
+
+// DefaultReloadInterval defines how often certificate files are checked for changes by default.
+const DefaultReloadInterval = time.Minute
+
+// Config defines transport security configuration. A service certificate is used both as
+// a server and as a client certificate, its DNS SAN must be equal to the service name.
+type Config struct {
+	// Mode is ModeInsecure, ModeTLS or ModeMTLS, ModeInsecure if empty.
+	Mode     string `yaml:"mode"`
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// CAFile is the CA bundle peer certificates are verified with, system roots are used if empty.
+	CAFile string `yaml:"caFile"`
+	// AllowedClients lists names of services allowed to connect in ModeMTLS,
+	// any client with a valid certificate is allowed if empty.
+	AllowedClients []string `yaml:"allowedClients"`
+	// ReloadInterval defines how often certificate files are checked for changes.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+// Validate checks the configuration consistency.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "", ModeInsecure:
+		return nil
+	case ModeTLS, ModeMTLS:
+	default:
+		return fmt.Errorf("unsupported mode %q", c.Mode)
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("certFile and keyFile must be set")
+	}
+	if c.Mode == ModeMTLS && c.CAFile == "" {
+		return errors.New("caFile must be set in mtls mode")
+	}
+	if c.ReloadInterval < 0 {
+		return errors.New("reloadInterval must not be negative")
+	}
+	return nil
+}
+
+// Credentials provides gRPC transport credentials backed by certificate files which are
+// reloaded on change, so that rotated certificates are picked up without a restart.
+type Credentials struct {
+	cfg    Config
+	logger *zap.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+	data []byte
+}
+
+// New creates new credentials loading certificate files. It returns nil in insecure mode.
+func New(cfg Config, logger *zap.Logger) (*Credentials, error) {
+	if cfg.Mode == "" || cfg.Mode == ModeInsecure {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = DefaultReloadInterval
+	}
+	c := &Credentials{cfg: cfg, logger: logger}
+	if _, err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload loads certificate files if they have changed and reports whether they have.
+// The current certificates are kept if the files are invalid.
+func (c *Credentials) Reload() (bool, error) {
+	var data bytes.Buffer
+	files := [][]byte{}
+	for _, path := range []string{c.cfg.CertFile, c.cfg.KeyFile, c.cfg.CAFile} {
+		if path == "" {
+			files = append(files, nil)
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		files = append(files, b)
+		data.Write(b)
+	}
+	c.mu.RLock()
+	unchanged := bytes.Equal(data.Bytes(), c.data)
+	c.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(files[0], files[1])
+	if err != nil {
+		return false, fmt.Errorf("load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if files[2] != nil {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(files[2]) {
+			return false, fmt.Errorf("no certificates in %s", c.cfg.CAFile)
+		}
+	} else if pool, err = x509.SystemCertPool(); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert, c.pool, c.data = &cert, pool, data.Bytes()
+	return true, nil
+}
+
+// Run checks certificate files for changes until ctx is done.
+func (c *Credentials) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if changed, err := c.Reload(); err != nil {
+			c.logger.Error("Failed to reload certificates", zap.Error(err))
+		} else if changed {
+			c.logger.Info("Reloaded certificates", zap.String("certFile", c.cfg.CertFile))
+		}
+	}
+}
+
+// ServerCredentials returns gRPC server transport credentials. In ModeMTLS client certificates
+// are required and, if AllowedClients is set, must be issued to one of the allowed services.
+func (c *Credentials) ServerCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			cert, pool := c.cert, c.pool
+			c.mu.RUnlock()
+			cfg := &tls.Config{MinVersion: tls.VersionTLS12, NextProtos: []string{"h2"}, Certificates: []tls.Certificate{*cert}}
+			if c.cfg.Mode == ModeMTLS {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pool
+				cfg.VerifyConnection = c.verifyClient
+			}
+			return cfg, nil
+		},
+	})
+}
+
+// ClientCredentials returns gRPC client transport credentials for connections to the service.
+// The server certificate must be issued to the service name. In ModeMTLS the service certificate
+// is presented to the server.
+func (c *Credentials) ClientCredentials(serviceName string) credentials.TransportCredentials {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serviceName,
+		// The chain is verified by VerifyConnection against the current CA pool,
+		// as RootCAs can't be replaced once certificates are rotated.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return c.verify(cs, serviceName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+	if c.cfg.Mode == ModeMTLS {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+			return c.cert, nil
+		}
+	}
+	return credentials.NewTLS(cfg)
+}
+
+func (c *Credentials) verifyClient(cs tls.ConnectionState) error {
+	if len(c.cfg.AllowedClients) == 0 {
+		return c.verify(cs, "", x509.ExtKeyUsageClientAuth)
+	}
+	var err error
+	for _, name := range c.cfg.AllowedClients {
+		if err = c.verify(cs, name, x509.ExtKeyUsageClientAuth); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("client is not allowed: %w", err)
+}
+
+// verify checks the peer certificate chain against the current CA pool and, if name
+// is not empty, that the certificate is issued to it.
+func (c *Credentials) verify(cs tls.ConnectionState, name string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	c.mu.RLock()
+	pool := c.pool
+	c.mu.RUnlock()
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,154 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCA{cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a service certificate and key to dir and returns the configuration using them.
+func (ca *testCA) issue(t *testing.T, dir string, serviceName string, mode string) Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: serviceName},
+		DNSNames:     []string{serviceName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	cfg := Config{
+		Mode:     mode,
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	assert.NoError(t, os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	assert.NoError(t, os.WriteFile(cfg.CAFile, ca.pem, 0o600))
+	return cfg
+}
+
+func serve(t *testing.T, creds credentials.TransportCredentials) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func check(addr string, creds credentials.TransportCredentials) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func newCredentials(t *testing.T, cfg Config) *Credentials {
+	c, err := New(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	return c
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCfg := ca.issue(t, t.TempDir(), "metadata", ModeMTLS)
+	serverCfg.AllowedClients = []string{"movie"}
+	addr := serve(t, newCredentials(t, serverCfg).ServerCredentials())
+
+	movie := newCredentials(t, ca.issue(t, t.TempDir(), "movie", ModeMTLS))
+	assert.NoError(t, check(addr, movie.ClientCredentials("metadata")))
+	assert.Error(t, check(addr, movie.ClientCredentials("rating")), "server identity is verified")
+
+	rating := newCredentials(t, ca.issue(t, t.TempDir(), "rating", ModeMTLS))
+	assert.Error(t, check(addr, rating.ClientCredentials("metadata")), "client identity is verified")
+
+	untrusted := newCredentials(t, newTestCA(t).issue(t, t.TempDir(), "movie", ModeMTLS))
+	assert.Error(t, check(addr, untrusted.ClientCredentials("metadata")), "client certificate must be issued by the CA")
+
+	tlsOnly := newCredentials(t, ca.issue(t, t.TempDir(), "movie", ModeTLS))
+	assert.Error(t, check(addr, tlsOnly.ClientCredentials("metadata")), "client certificate is required")
+}
+
+func TestReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	server := newCredentials(t, ca.issue(t, dir, "metadata", ModeTLS))
+	addr := serve(t, server.ServerCredentials())
+	client := newCredentials(t, ca.issue(t, t.TempDir(), "movie", ModeTLS))
+	assert.NoError(t, check(addr, client.ClientCredentials("metadata")))
+
+	// Rotate the CA and the server certificate, the client trusts the old CA only.
+	newCA := newTestCA(t)
+	newCA.issue(t, dir, "metadata", ModeTLS)
+	changed, err := server.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Error(t, check(addr, client.ClientCredentials("metadata")), "rotated certificate is served")
+
+	assert.NoError(t, os.WriteFile(client.cfg.CAFile, newCA.pem, 0o600))
+	_, err = client.Reload()
+	assert.NoError(t, err)
+	assert.NoError(t, check(addr, client.ClientCredentials("metadata")), "rotated CA is trusted")
+
+	assert.NoError(t, os.WriteFile(server.cfg.CertFile, []byte("invalid"), 0o600))
+	_, err = server.Reload()
+	assert.Error(t, err)
+	assert.NoError(t, check(addr, client.ClientCredentials("metadata")), "invalid files are ignored")
+}
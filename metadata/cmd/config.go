@@ -1,14 +1,37 @@
 package main
 
+import (
+	"github.com/mkvy/movies-app/internal/auditutil"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
+)
+
 type config struct {
-	API apiConfig `yaml:"api"`
-	Jaeger jaegerConfig `yaml:"jaeger"`
+	service.Config `yaml:",inline"`
+	Database       databaseConfig   `yaml:"database"`
+	Audit          auditutil.Config `yaml:"audit"`
+}
+
+type databaseConfig struct {
+	DSN string `yaml:"dsn" secret:"true"`
 }
 
-type apiConfig struct {
-	Port int `yaml:"port"`
+func defaultConfig() config {
+	return config{
+		Config:   service.DefaultConfig(),
+		Database: databaseConfig{DSN: "root:password@/movieexample"},
+	}
 }
 
-type jaegerConfig struct {
-	URL string `yaml:"url"`
-}
\ No newline at end of file
+// Validate checks the configuration consistency.
+func (c config) Validate() error {
+	var errs appconfig.Errors
+	errs.Add(c.Config.Validate())
+	if c.Database.DSN == "" {
+		errs.Addf("database.dsn must be set")
+	}
+	if err := c.Audit.Validate(); err != nil {
+		errs.Addf("audit: %v", err)
+	}
+	return errs.Err()
+}
@@ -2,27 +2,16 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/auditutil"
 	"github.com/mkvy/movies-app/metadata/internal/controller/metadata"
 	grpchandler "github.com/mkvy/movies-app/metadata/internal/handler/grpc"
+	httphandler "github.com/mkvy/movies-app/metadata/internal/handler/http"
 	"github.com/mkvy/movies-app/metadata/internal/repository/mysql"
-	"github.com/mkvy/movies-app/pkg/discovery"
-	"github.com/mkvy/movies-app/pkg/discovery/consul"
-	"github.com/mkvy/movies-app/pkg/tracing"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
 	"go.uber.org/zap"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
-	"net"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
 )
 
 const serviceName = "metadata"
@@ -30,80 +19,50 @@ const serviceName = "metadata"
 func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
-	// if not docker image:
-	f, err := os.Open("./metadata/configs/base.yaml")
-	//if docker
-	//f, err := os.Open("base.yaml")
-	if err != nil {
-		logger.Fatal("Failed to open configuration", zap.Error(err))
+	cfg := defaultConfig()
+	loader := appconfig.NewLoader(serviceName, "./metadata/configs")
+	if err := loader.Load(&cfg, os.Args[1:]); err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
-	var cfg config
-	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
-		logger.Fatal("Failed to parse configuration", zap.Error(err))
+	if loader.PrintRequested() {
+		if err := appconfig.Print(os.Stdout, cfg); err != nil {
+			logger.Fatal("Failed to print configuration", zap.Error(err))
+		}
+		return
 	}
-	port := cfg.API.Port
-
-	logger.Info("Starting the metadata service", zap.Int("port", port))
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := tracing.NewJaegerProvider(cfg.Jaeger.URL, serviceName)
+	ctx := context.Background()
+	svc, err := service.New(ctx, serviceName, cfg.Config)
 	if err != nil {
-		logger.Fatal("Failed to initialize Jaeger provider", zap.Error(err))
+		logger.Fatal("Failed to initialize the metadata service", zap.Error(err))
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			logger.Fatal("Failed to shut down Jaeger provider", zap.Error(err))
-		}
-	}()
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	registry, err := consul.NewRegistry("localhost:8500")
+	logger = svc.Logger()
+	repo, err := mysql.New(cfg.Database.DSN)
 	if err != nil {
-		panic(err)
+		logger.Fatal("Failed to initialize repository", zap.Error(err))
 	}
-
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%d", port)); err != nil {
-		panic(err)
-	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				logger.Error("Failed to report healthy state", zap.Error(err))
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-	repo, err := mysql.New()
+	svc.AddReadinessCheck("database", repo.Ping)
+	auditor, err := auditutil.NewLogger(cfg.Audit, cfg.Database.DSN)
 	if err != nil {
-		panic(err)
+		logger.Fatal("Failed to initialize audit log", zap.Error(err))
 	}
-	ctrl := metadata.New(repo)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
-	if err != nil {
-		logger.Fatal("Failed to listen", zap.Error(err))
+	if cfg.Audit.Enabled() {
+		svc.Handle("/v1/audit", auditor.Handler())
 	}
-	srv := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()))
-	reflection.Register(srv)
-	gen.RegisterMetadataServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		_ = <-sigChan
-		cancel()
-		logger.Info("Attempting graceful shutdown")
-		srv.GracefulStop()
-		logger.Info("Gracefully stopped the gRPC server")
-	}()
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	ctrl := metadata.New(repo, auditor)
+	watcher := appconfig.NewWatcher(loader, cfg, defaultConfig, logger)
+	svc.Admin().Publish("/config", func() any {
+		return appconfig.Redact(watcher.Current())
+	})
+	watcher.Subscribe("service", func(cfg config) error {
+		return svc.Reconfigure(cfg.Config)
+	})
+	svc.Go("config watcher", func(ctx context.Context) {
+		watcher.Run(ctx, cfg.Lifecycle.ReloadInterval)
+	})
+	h := grpchandler.New(ctrl)
+	gen.RegisterMetadataServiceServer(svc.Server(), h)
+	svc.HandleREST(httphandler.Routes(h)...)
+	if err := svc.Run(ctx); err != nil {
+		logger.Fatal("Failed to run the metadata service", zap.Error(err))
 	}
-	wg.Wait()
 }
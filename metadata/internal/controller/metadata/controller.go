@@ -3,30 +3,58 @@ package metadata
 import (
 	"context"
 	"errors"
+	"github.com/mkvy/movies-app/internal/pubsub"
 	"github.com/mkvy/movies-app/metadata/internal/repository"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"github.com/mkvy/movies-app/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"time"
 )
 
+var tracer = otel.Tracer("github.com/mkvy/movies-app/metadata/internal/controller/metadata")
+
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
 
 type metadataRepository interface {
 	Get(ctx context.Context, id string) (*model.Metadata, error)
 	Put(ctx context.Context, id string, m *model.Metadata) error
+	Search(ctx context.Context, query string, limit int) ([]*model.Metadata, error)
+}
+
+type auditor interface {
+	Record(ctx context.Context, action string, target string, before any, after any) error
 }
 
+// Audited actions.
+const (
+	ActionPut    = "metadata.put"
+	ActionUpdate = "metadata.update"
+)
+
+// watchPollInterval bounds the delay of watchers noticing changes made through other instances.
+const watchPollInterval = 5 * time.Second
+
 // Controller defines a metadata service controller.
 type Controller struct {
-	repo metadataRepository
+	repo    metadataRepository
+	auditor auditor
+	changes *pubsub.Broker
 }
 
-// New is a factory for Controller.
-func New(repo metadataRepository) *Controller {
-	return &Controller{repo}
+// New is a factory for Controller. Auditor is optional, changes aren't audited if it is nil.
+func New(repo metadataRepository, auditor auditor) *Controller {
+	return &Controller{repo: repo, auditor: auditor, changes: pubsub.New()}
 }
 
 // Get returns movie metadata by id.
-func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error) {
+func (c *Controller) Get(ctx context.Context, id string) (_ *model.Metadata, err error) {
+	ctx, span := tracer.Start(ctx, "metadata.Controller.Get")
+	span.SetAttributes(attribute.String("metadata.id", id))
+	defer tracing.End(span, &err, ErrNotFound)
 	res, err := c.repo.Get(ctx, id)
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrNotFound
@@ -34,7 +62,81 @@ func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error
 	return res, err
 }
 
-// Put writes movie metadata to repository.
-func (c *Controller) Put(ctx context.Context, m *model.Metadata) error {
-	return c.repo.Put(ctx, m.ID, m)
+// GetMany returns metadata of the movies with the given ids, unknown movies are omitted.
+func (c *Controller) GetMany(ctx context.Context, ids []string) (_ []*model.Metadata, err error) {
+	ctx, span := tracer.Start(ctx, "metadata.Controller.GetMany")
+	span.SetAttributes(attribute.Int("metadata.count", len(ids)))
+	defer tracing.End(span, &err)
+	var res []*model.Metadata
+	for _, id := range ids {
+		m, err := c.repo.Get(ctx, id)
+		if err != nil && errors.Is(err, repository.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// Search returns up to limit movies whose title, description or director contain the query.
+func (c *Controller) Search(ctx context.Context, query string, limit int) (_ []*model.Metadata, err error) {
+	ctx, span := tracer.Start(ctx, "metadata.Controller.Search")
+	span.SetAttributes(attribute.String("metadata.query", query))
+	defer tracing.End(span, &err)
+	return c.repo.Search(ctx, query, limit)
+}
+
+// Put writes movie metadata to repository and records the change in the audit log.
+// Failures to record the change are logged, as the change is already applied.
+func (c *Controller) Put(ctx context.Context, m *model.Metadata) (err error) {
+	ctx, span := tracer.Start(ctx, "metadata.Controller.Put")
+	span.SetAttributes(attribute.String("metadata.id", m.ID))
+	defer tracing.End(span, &err)
+	if c.auditor == nil {
+		if err := c.repo.Put(ctx, m.ID, m); err != nil {
+			return err
+		}
+		c.changes.Publish(m.ID)
+		return nil
+	}
+	before, err := c.repo.Get(ctx, m.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err := c.repo.Put(ctx, m.ID, m); err != nil {
+		return err
+	}
+	c.changes.Publish(m.ID)
+	action := ActionPut
+	if before != nil {
+		action = ActionUpdate
+	}
+	span.SetAttributes(attribute.String("audit.action", action))
+	target := "metadata/" + m.ID
+	if err := c.auditor.Record(ctx, action, target, before, m); err != nil {
+		span.RecordError(err)
+		logging.FromContext(ctx).Error("Failed to record audit entry", zap.String("action", action), zap.String("target", target), zap.Error(err))
+	}
+	return nil
+}
+
+// Watch calls send with the metadata of the movie and then again after each change, until ctx is done or send fails.
+// Changes made through other instances are noticed by polling the repository.
+func (c *Controller) Watch(ctx context.Context, id string, send func(*model.Metadata) error) error {
+	var last *model.Metadata
+	return c.changes.Watch(ctx, id, watchPollInterval, func(ctx context.Context) error {
+		m, err := c.repo.Get(ctx, id)
+		if err != nil && errors.Is(err, repository.ErrNotFound) && last == nil {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if last != nil && *last == *m {
+			return nil
+		}
+		last = m
+		return send(m)
+	})
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/golang/mock/gomock"
 	gen "github.com/mkvy/movies-app/gen/mock/metadata/repository"
 	"github.com/mkvy/movies-app/metadata/internal/repository"
+	"github.com/mkvy/movies-app/metadata/internal/repository/memory"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -40,13 +41,65 @@ func TestController(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			repoMock := gen.NewMockmetadataRepository(ctrl)
-			c := New(repoMock)
+			c := New(repoMock, nil)
 			ctx := context.Background()
 			id := "id"
-			repoMock.EXPECT().Get(ctx, id).Return(tt.expRepoRes, tt.expRepoErr)
+			// The controller passes a context carrying its span.
+			repoMock.EXPECT().Get(gomock.Any(), id).Return(tt.expRepoRes, tt.expRepoErr)
 			res, err := c.Get(ctx, id)
 			assert.Equal(t, tt.wantRes, res, tt.name)
 			assert.Equal(t, tt.wantErr, err, tt.name)
 		})
 	}
 }
+
+func TestGetMany(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repoMock := gen.NewMockmetadataRepository(ctrl)
+	found := &model.Metadata{ID: "found"}
+	repoMock.EXPECT().Get(gomock.Any(), "found").Return(found, nil)
+	repoMock.EXPECT().Get(gomock.Any(), "missing").Return(nil, repository.ErrNotFound)
+	res, err := New(repoMock, nil).GetMany(context.Background(), []string{"missing", "found"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Metadata{found}, res)
+}
+
+func TestWatch(t *testing.T) {
+	c := New(memory.New(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Equal(t, ErrNotFound, c.Watch(ctx, "1", func(*model.Metadata) error { return nil }))
+
+	assert.NoError(t, c.Put(ctx, &model.Metadata{ID: "1", Title: "A"}))
+	updates := make(chan *model.Metadata)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Watch(ctx, "1", func(m *model.Metadata) error {
+			updates <- m
+			return nil
+		})
+	}()
+	assert.Equal(t, "A", (<-updates).Title)
+	// Unchanged metadata isn't sent again.
+	assert.NoError(t, c.Put(ctx, &model.Metadata{ID: "1", Title: "A"}))
+	assert.NoError(t, c.Put(ctx, &model.Metadata{ID: "1", Title: "B"}))
+	assert.Equal(t, "B", (<-updates).Title)
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+type failingAuditor struct{}
+
+func (failingAuditor) Record(context.Context, string, string, any, any) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestPutAuditFailure(t *testing.T) {
+	c := New(memory.New(), failingAuditor{})
+	ctx := context.Background()
+	assert.NoError(t, c.Put(ctx, &model.Metadata{ID: "1", Title: "A"}), "the change is applied")
+	m, err := c.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", m.Title)
+}
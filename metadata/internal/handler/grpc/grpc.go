@@ -10,6 +10,13 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// Limits of batch and search requests.
+const (
+	maxBatchSize       = 100
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
 // Handler defines a movie metadata gRPC handler.
 type Handler struct {
 	gen.UnimplementedMetadataServiceServer
@@ -45,3 +52,58 @@ func (h *Handler) PutMetadata(ctx context.Context, req *gen.PutMetadataRequest)
 	}
 	return &gen.PutMetadataResponse{}, nil
 }
+
+// BatchGetMetadata returns metadata of multiple movies, unknown movies are omitted.
+func (h *Handler) BatchGetMetadata(ctx context.Context, req *gen.BatchGetMetadataRequest) (*gen.BatchGetMetadataResponse, error) {
+	if req == nil || len(req.MovieIds) == 0 || len(req.MovieIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or not between 1 and %d ids", maxBatchSize)
+	}
+	res, err := h.ctrl.GetMany(ctx, req.MovieIds)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.BatchGetMetadataResponse{Metadata: metadataToProto(res)}, nil
+}
+
+// SearchMetadata returns movies matching a query, 20 by default.
+func (h *Handler) SearchMetadata(ctx context.Context, req *gen.SearchMetadataRequest) (*gen.SearchMetadataResponse, error) {
+	if req == nil || req.Query == "" || req.Limit < 0 || req.Limit > maxSearchLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req, empty query or limit above %d", maxSearchLimit)
+	}
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	res, err := h.ctrl.Search(ctx, req.Query, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.SearchMetadataResponse{Metadata: metadataToProto(res)}, nil
+}
+
+// WatchMetadata streams the metadata of a movie and its changes until the client cancels the call.
+func (h *Handler) WatchMetadata(req *gen.WatchMetadataRequest, stream gen.MetadataService_WatchMetadataServer) error {
+	if req == nil || req.MovieId == "" {
+		return status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	ctx := stream.Context()
+	err := h.ctrl.Watch(ctx, req.MovieId, func(m *model.Metadata) error {
+		return stream.Send(&gen.WatchMetadataResponse{Metadata: model.MetadataToProto(m)})
+	})
+	if err != nil && errors.Is(err, metadata.ErrNotFound) {
+		return status.Errorf(codes.NotFound, err.Error())
+	} else if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	} else if _, ok := status.FromError(err); !ok {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	return err
+}
+
+func metadataToProto(ms []*model.Metadata) []*gen.Metadata {
+	res := make([]*gen.Metadata, 0, len(ms))
+	for _, m := range ms {
+		res = append(res, model.MetadataToProto(m))
+	}
+	return res
+}
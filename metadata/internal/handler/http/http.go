@@ -1,43 +1,28 @@
 package http
 
 import (
-	"encoding/json"
-	"errors"
-	"github.com/mkvy/movies-app/metadata/internal/controller/metadata"
-	"log"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/rest"
 	"net/http"
 )
 
-// Handler defines a movie metadata HTTP handler.
-type Handler struct {
-	ctrl *metadata.Controller
-}
-
-// New is a factory for handler.
-func New(ctrl *metadata.Controller) *Handler {
-	return &Handler{ctrl}
-}
-
-// GetMetadata handles GET /metadata requests.
-func (h *Handler) GetMetadata(w http.ResponseWriter, req *http.Request) {
-	id := req.FormValue("id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// Routes returns REST routes of the metadata service mapped onto its gRPC methods.
+func Routes(h gen.MetadataServiceServer) []rest.Route {
+	return []rest.Route{
+		{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/metadata/{movie_id}",
+			RPC:          gen.MetadataService_GetMetadata_FullMethodName,
+			Call:         rest.Unary(h.GetMetadata),
+			ResponseBody: "metadata",
+		},
+		{
+			Method:  http.MethodPut,
+			Pattern: "/v1/metadata/{metadata.id}",
+			RPC:     gen.MetadataService_PutMetadata_FullMethodName,
+			Call:    rest.Unary(h.PutMetadata),
+			Body:    "metadata",
+			Status:  http.StatusNoContent,
+		},
 	}
-	ctx := req.Context()
-	m, err := h.ctrl.Get(ctx, id)
-	if err != nil && errors.Is(err, metadata.ErrNotFound) {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
-	if err != nil {
-		log.Printf("Repository got error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if err := json.NewEncoder(w).Encode(m); err != nil {
-		log.Printf("Response encode error: %v\n", err)
-	}
-
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/mkvy/movies-app/metadata/internal/repository"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -36,3 +38,29 @@ func (r *Repository) Put(_ context.Context, id string, metadata *model.Metadata)
 	r.data[id] = metadata
 	return nil
 }
+
+// Search returns up to limit movies whose title, description or director contain the query, ignoring case.
+// Results are ordered by title.
+func (r *Repository) Search(_ context.Context, query string, limit int) ([]*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+	query = strings.ToLower(query)
+	var res []*model.Metadata
+	for _, m := range r.data {
+		if strings.Contains(strings.ToLower(m.Title), query) ||
+			strings.Contains(strings.ToLower(m.Description), query) ||
+			strings.Contains(strings.ToLower(m.Director), query) {
+			res = append(res, m)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Title != res[j].Title {
+			return res[i].Title < res[j].Title
+		}
+		return res[i].ID < res[j].ID
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
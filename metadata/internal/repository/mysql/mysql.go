@@ -4,26 +4,45 @@ import (
 	"context"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/mkvy/movies-app/internal/metrics"
 	"github.com/mkvy/movies-app/metadata/internal/repository"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
 )
 
+var tracer = otel.Tracer("github.com/mkvy/movies-app/metadata/internal/repository/mysql")
+
 // Repository defines a MySQL-based movie matadata repository.
 type Repository struct {
 	db *sql.DB
 }
 
 // New creates a new MySQL-based repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movieexample")
+// The dsn is in the go-sql-driver format, e.g. root:password@/movieexample.
+func New(dsn string) (*Repository, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
 	return &Repository{db}, nil
 }
 
+// Ping checks the database connection, e.g. for readiness checks.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
 // Get retrieves movie metadata for by movie id.
-func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+func (r *Repository) Get(ctx context.Context, id string) (_ *model.Metadata, err error) {
+	defer metrics.ObserveRepository("mysql", "get", time.Now(), &err, repository.ErrNotFound)
+	ctx, span := startSpan(ctx, "SELECT", id)
+	defer tracing.End(span, &err, repository.ErrNotFound)
 	var title, description, director string
 	row := r.db.QueryRowContext(ctx, "SELECT title, description, director FROM movies WHERE id = ?", id)
 	if err := row.Scan(&title, &description, &director); err != nil {
@@ -40,9 +59,57 @@ func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error
 	}, nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO movies (id, title, description, director) VALUES (?, ?, ?, ?)",
+// Put adds or replaces movie metadata for a given movie id.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) (err error) {
+	defer metrics.ObserveRepository("mysql", "put", time.Now(), &err, nil)
+	ctx, span := startSpan(ctx, "INSERT", id)
+	defer tracing.End(span, &err)
+	_, err = r.db.ExecContext(ctx, "INSERT INTO movies (id, title, description, director) VALUES (?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE title = VALUES(title), description = VALUES(description), director = VALUES(director)",
 		id, metadata.Title, metadata.Description, metadata.Director)
 	return err
 }
+
+// Search returns up to limit movies whose title, description or director contain the query, ignoring case.
+// Results are ordered by title.
+func (r *Repository) Search(ctx context.Context, query string, limit int) (_ []*model.Metadata, err error) {
+	defer metrics.ObserveRepository("mysql", "search", time.Now(), &err, nil)
+	ctx, span := tracer.Start(ctx, "SELECT movies",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String("SELECT"),
+			semconv.DBSQLTableKey.String("movies"),
+		),
+	)
+	defer tracing.End(span, &err)
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(query)) + "%"
+	rows, err := r.db.QueryContext(ctx, "SELECT id, title, description, director FROM movies "+
+		"WHERE LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(director) LIKE ? ORDER BY title, id LIMIT ?",
+		pattern, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.Metadata
+	for rows.Next() {
+		m := &model.Metadata{}
+		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Director); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, rows.Err()
+}
+
+func startSpan(ctx context.Context, operation string, id string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation+" movies",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String("movies"),
+			attribute.String("metadata.id", id),
+		),
+	)
+}
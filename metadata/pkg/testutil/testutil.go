@@ -10,6 +10,6 @@ import (
 // NewTestMetadataGRPCServer creates a new metadata gRPC server to be used in tests.
 func NewTestMetadataGRPCServer() gen.MetadataServiceServer {
 	r := memory.New()
-	ctrl := metadata.New(r)
+	ctrl := metadata.New(r, nil)
 	return grpchandler.New(ctrl)
 }
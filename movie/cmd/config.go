@@ -1,13 +1,74 @@
 package main
 
+import (
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/internal/hedging"
+	metadatagateway "github.com/mkvy/movies-app/movie/internal/gateway/metadata/grpc"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
+)
+
 type config struct {
-	API apiConfig `yaml:"api"`
-	Jaeger jaegerConfig `yaml:"jaeger"`
+	service.Config `yaml:",inline"`
+	Hedging        hedgingConfig `yaml:"hedging"`
+	Cache          cacheConfig   `yaml:"cache"`
+	Retry          retryConfig   `yaml:"retry"`
+}
+
+type hedgingConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	hedging.Config `yaml:",inline"`
+}
+
+type cacheConfig struct {
+	Enabled  bool         `yaml:"enabled"`
+	Metadata cache.Config `yaml:"metadata"`
+	Rating   cache.Config `yaml:"rating"`
+}
+
+type retryConfig struct {
+	// MaxAttempts is the number of attempts of a metadata or rating request failing with a retryable error.
+	MaxAttempts int `yaml:"maxAttempts"`
 }
-type apiConfig struct {
-	Port int `yaml:"port"`
+
+func defaultConfig() config {
+	return config{
+		Config:  service.DefaultConfig(),
+		Hedging: hedgingConfig{Config: hedging.DefaultConfig()},
+		Retry:   retryConfig{MaxAttempts: metadatagateway.DefaultMaxAttempts},
+	}
 }
 
-type jaegerConfig struct {
-	URL string `yaml:"url"`
+// Validate checks the configuration consistency.
+func (c config) Validate() error {
+	var errs appconfig.Errors
+	errs.Add(c.Config.Validate())
+	if c.Hedging.Enabled {
+		if c.Hedging.Percentile <= 0 || c.Hedging.Percentile >= 1 {
+			errs.Addf("hedging.percentile must be between 0 and 1, got %v", c.Hedging.Percentile)
+		}
+		if c.Hedging.MinDelay > c.Hedging.MaxDelay {
+			errs.Addf("hedging.minDelay must not exceed hedging.maxDelay")
+		}
+		if c.Hedging.BudgetRatio < 0 || c.Hedging.BudgetRatio > 1 {
+			errs.Addf("hedging.budgetRatio must be between 0 and 1, got %v", c.Hedging.BudgetRatio)
+		}
+	}
+	if c.Cache.Enabled {
+		for _, cc := range []struct {
+			name string
+			cfg  cache.Config
+		}{{"metadata", c.Cache.Metadata}, {"rating", c.Cache.Rating}} {
+			if cc.cfg.Size <= 0 || cc.cfg.TTL <= 0 || cc.cfg.NegativeTTL < 0 {
+				errs.Addf("cache.%[1]s.size and cache.%[1]s.ttl must be positive and cache.%[1]s.negativeTTL must not be negative", cc.name)
+			}
+			if cc.cfg.LoadTimeout < 0 {
+				errs.Addf("cache.%s.loadTimeout must not be negative", cc.name)
+			}
+		}
+	}
+	if c.Retry.MaxAttempts < 1 {
+		errs.Addf("retry.maxAttempts must be positive")
+	}
+	return errs.Err()
 }
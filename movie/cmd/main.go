@@ -2,127 +2,113 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/internal/health"
+	"github.com/mkvy/movies-app/internal/hedging"
 	"github.com/mkvy/movies-app/movie/internal/controller/movie"
-	"github.com/mkvy/movies-app/pkg/tracing"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
-	"go.uber.org/zap"
-	"golang.org/x/time/rate"
-	"os/signal"
-	"sync"
-	"syscall"
-
+	"github.com/mkvy/movies-app/movie/internal/gateway/cached"
 	metadatagateway "github.com/mkvy/movies-app/movie/internal/gateway/metadata/grpc"
 	ratinggateway "github.com/mkvy/movies-app/movie/internal/gateway/rating/grpc"
+	graphqlhandler "github.com/mkvy/movies-app/movie/internal/handler/graphql"
 	grpchandler "github.com/mkvy/movies-app/movie/internal/handler/grpc"
-	"github.com/mkvy/movies-app/pkg/discovery"
-	"github.com/mkvy/movies-app/pkg/discovery/consul"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
-	"net"
+	httphandler "github.com/mkvy/movies-app/movie/internal/handler/http"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
+	"go.uber.org/zap"
 	"os"
-	"time"
 )
 
 const serviceName = "movie"
-const registryConsulAddr = "localhost:8500"
 
 func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	// if not docker image:
-	f, err := os.Open("./movie/configs/base.yaml")
-	// if docker
-	//f, err := os.Open("base.yaml")
-	if err != nil {
-		logger.Fatal("Failed to open configuration", zap.Error(err))
+	cfg := defaultConfig()
+	loader := appconfig.NewLoader(serviceName, "./movie/configs")
+	if err := loader.Load(&cfg, os.Args[1:]); err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
-	var cfg config
-	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
-		logger.Fatal("Failed to parse configuration", zap.Error(err))
+	if loader.PrintRequested() {
+		if err := appconfig.Print(os.Stdout, cfg); err != nil {
+			logger.Fatal("Failed to print configuration", zap.Error(err))
+		}
+		return
 	}
-	port := cfg.API.Port
-
-	logger.Info("Starting the movie service", zap.Int("port", port))
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := tracing.NewJaegerProvider(cfg.Jaeger.URL, serviceName)
+	ctx := context.Background()
+	svc, err := service.New(ctx, serviceName, cfg.Config)
 	if err != nil {
-		logger.Fatal("Failed to initialize Jaeger provider", zap.Error(err))
+		logger.Fatal("Failed to initialize the movie service", zap.Error(err))
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			logger.Fatal("Failed to shut down Jaeger provider", zap.Error(err))
-		}
-	}()
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	registry, err := consul.NewRegistry(registryConsulAddr)
-	if err != nil {
-		panic(err)
+	logger = svc.Logger()
+	for _, upstream := range []string{"metadata", "rating"} {
+		svc.AddReadinessCheck("registry:"+upstream, health.ServiceLookup(svc.Registry(), upstream))
 	}
 
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%d", port)); err != nil {
-		panic(err)
+	var metadataHedger, ratingHedger *hedging.Hedger
+	if cfg.Hedging.Enabled {
+		metadataHedger = hedging.New(cfg.Hedging.Config)
+		ratingHedger = hedging.New(cfg.Hedging.Config)
+		svc.Admin().Publish("/hedging", func() any {
+			return map[string]hedging.State{"metadata": metadataHedger.State(), "rating": ratingHedger.State()}
+		})
 	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				logger.Error("Failed to report healthy state", zap.Error(err))
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
+	metadataGateway := metadatagateway.New(svc.Registry(), metadataHedger, svc.ClientCredentials("metadata"))
+	metadataGateway.SetMaxAttempts(cfg.Retry.MaxAttempts)
+	ratingGateway := ratinggateway.New(svc.Registry(), ratingHedger, svc.ClientCredentials("rating"))
+	ratingGateway.SetMaxAttempts(cfg.Retry.MaxAttempts)
 	ctrl := movie.New(ratingGateway, metadataGateway)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
-	if err != nil {
-		logger.Fatal("Failed to listen", zap.Error(err))
+	var cachedMetadataGateway *cached.MetadataGateway
+	var cachedRatingGateway *cached.RatingGateway
+	// GraphQL queries batch upstream requests and aren't cached, its mutations invalidate cached movie details.
+	var graphqlHandler *graphqlhandler.Handler
+	if cfg.Cache.Enabled {
+		cachedMetadataGateway = cached.NewMetadataGateway(metadataGateway, cfg.Cache.Metadata, nil)
+		cachedRatingGateway = cached.NewRatingGateway(ratingGateway, cfg.Cache.Rating, nil)
+		ctrl = movie.New(cachedRatingGateway, cachedMetadataGateway)
+		graphqlHandler = graphqlhandler.New(cachedMetadataGateway, cachedRatingGateway)
+		svc.Admin().Publish("/cache", func() any {
+			return map[string]cache.State{"metadata": cachedMetadataGateway.State(), "rating": cachedRatingGateway.State()}
+		})
+	} else {
+		graphqlHandler = graphqlhandler.New(metadataGateway, ratingGateway)
 	}
-	//const limit = 100
-	//const burst = 100
-	//l := newLimiter(limit, burst)
-	srv := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()))
-	reflection.Register(srv)
-	gen.RegisterMovieServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		_ = <-sigChan
-		cancel()
-		logger.Info("Attempting graceful shutdown")
-		srv.GracefulStop()
-		logger.Info("Gracefully stopped the gRPC server")
-	}()
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	watcher := appconfig.NewWatcher(loader, cfg, defaultConfig, logger)
+	svc.Admin().Publish("/config", func() any {
+		return appconfig.Redact(watcher.Current())
+	})
+	watcher.Subscribe("service", func(cfg config) error {
+		return svc.Reconfigure(cfg.Config)
+	})
+	watcher.Subscribe("retry", func(cfg config) error {
+		metadataGateway.SetMaxAttempts(cfg.Retry.MaxAttempts)
+		ratingGateway.SetMaxAttempts(cfg.Retry.MaxAttempts)
+		return nil
+	})
+	// Enabling or disabling hedging and caching requires a restart, their parameters are applied at runtime.
+	watcher.Subscribe("hedging", func(cfg config) error {
+		if metadataHedger != nil && cfg.Hedging.Enabled {
+			metadataHedger.Update(cfg.Hedging.Config)
+			ratingHedger.Update(cfg.Hedging.Config)
+		}
+		return nil
+	})
+	watcher.Subscribe("cache", func(cfg config) error {
+		if cachedMetadataGateway != nil && cfg.Cache.Enabled {
+			cachedMetadataGateway.SetConfig(cfg.Cache.Metadata)
+			cachedRatingGateway.SetConfig(cfg.Cache.Rating)
+		}
+		return nil
+	})
+	svc.Go("config watcher", func(ctx context.Context) {
+		watcher.Run(ctx, cfg.Lifecycle.ReloadInterval)
+	})
+	h := grpchandler.New(ctrl)
+	gen.RegisterMovieServiceServer(svc.Server(), h)
+	svc.HandleREST(httphandler.Routes(h)...)
+	svc.Handle(graphqlhandler.Path, graphqlHandler)
+	if err := svc.Run(ctx); err != nil {
+		logger.Fatal("Failed to run the movie service", zap.Error(err))
 	}
-	wg.Wait()
-}
-
-type limiter struct {
-	l *rate.Limiter
-}
-
-func newLimiter(limit int, burst int) *limiter {
-	return &limiter{rate.NewLimiter(rate.Limit(limit), burst)}
-}
-
-func (l *limiter) Limit() bool {
-	return l.l.Allow()
 }
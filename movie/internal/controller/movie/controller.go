@@ -6,18 +6,25 @@ import (
 	metadatamodel "github.com/mkvy/movies-app/metadata/pkg/model"
 	"github.com/mkvy/movies-app/movie/internal/gateway"
 	"github.com/mkvy/movies-app/movie/pkg/model"
+	"github.com/mkvy/movies-app/pkg/tracing"
 	ratingmodel "github.com/mkvy/movies-app/rating/pkg/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = otel.Tracer("github.com/mkvy/movies-app/movie/internal/controller/movie")
+
 var ErrNotFound = errors.New("movie metadata not found")
 
 type ratingGateway interface {
 	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+	WatchAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, fn func(float64) error) error
 	//PutRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, rating *ratingmodel.Rating) error
 }
 
 type metadataGateway interface {
 	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	Watch(ctx context.Context, id string, fn func(*metadatamodel.Metadata) error) error
 }
 
 // Controller defines a movie service controller.
@@ -32,7 +39,10 @@ func New(ratingGateway ratingGateway, metadataGateway metadataGateway) *Controll
 }
 
 // Get returns the movie details including the aggregated rating and movie metadata.
-func (c *Controller) Get(ctx context.Context, id string) (*model.MovieDetails, error) {
+func (c *Controller) Get(ctx context.Context, id string) (_ *model.MovieDetails, err error) {
+	ctx, span := tracer.Start(ctx, "movie.Controller.Get")
+	span.SetAttributes(attribute.String("movie.id", id))
+	defer tracing.End(span, &err, ErrNotFound)
 	metadata, err := c.metadataGateway.Get(ctx, id)
 	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		return nil, ErrNotFound
@@ -41,13 +51,73 @@ func (c *Controller) Get(ctx context.Context, id string) (*model.MovieDetails, e
 	}
 	details := &model.MovieDetails{Metadata: *metadata}
 	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie)
-	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
-		// todo
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		// Just proceed in this case, it's ok not to have ratings yet.
 	} else if err != nil {
 		return nil, err
 	} else {
 		details.Rating = &rating
 	}
+	span.SetAttributes(attribute.Bool("movie.rated", details.Rating != nil))
 	return details, nil
 }
+
+// Watch calls send with the movie details and then again after each change of the metadata or aggregated rating,
+// until ctx is done, send fails or watching upstream services fails with an error that can't be retried.
+func (c *Controller) Watch(ctx context.Context, id string, send func(*model.MovieDetails) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	details, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := send(details); err != nil {
+		return err
+	}
+	// Upstream streams send their current values first, updates that don't change the details are dropped.
+	updates := make(chan func(*model.MovieDetails))
+	push := func(update func(*model.MovieDetails)) error {
+		select {
+		case updates <- update:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	errs := make(chan error, 2)
+	go func() {
+		errs <- c.metadataGateway.Watch(ctx, id, func(m *metadatamodel.Metadata) error {
+			return push(func(d *model.MovieDetails) { d.Metadata = *m })
+		})
+	}()
+	go func() {
+		errs <- c.ratingGateway.WatchAggregatedRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie, func(v float64) error {
+			return push(func(d *model.MovieDetails) { d.Rating = &v })
+		})
+	}()
+	for {
+		select {
+		case update := <-updates:
+			next := *details
+			update(&next)
+			if next.Metadata == details.Metadata && equalRatings(next.Rating, details.Rating) {
+				continue
+			}
+			details = &next
+			if err := send(details); err != nil {
+				return err
+			}
+		case err := <-errs:
+			if err != nil && errors.Is(err, gateway.ErrNotFound) {
+				return ErrNotFound
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func equalRatings(a, b *float64) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
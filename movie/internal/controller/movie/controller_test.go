@@ -0,0 +1,145 @@
+package movie
+
+import (
+	"context"
+	"errors"
+	metadatamodel "github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
+	"github.com/mkvy/movies-app/movie/pkg/model"
+	ratingmodel "github.com/mkvy/movies-app/rating/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// metadataStub returns fixed metadata, its Watch calls fn with values of updates until it receives from watchErr.
+type metadataStub struct {
+	metadata *metadatamodel.Metadata
+	err      error
+	updates  chan *metadatamodel.Metadata
+	watchErr chan error
+}
+
+func (s *metadataStub) Get(context.Context, string) (*metadatamodel.Metadata, error) {
+	return s.metadata, s.err
+}
+
+func (s *metadataStub) Watch(ctx context.Context, _ string, fn func(*metadatamodel.Metadata) error) error {
+	for {
+		select {
+		case m := <-s.updates:
+			if err := fn(m); err != nil {
+				return err
+			}
+		case err := <-s.watchErr:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ratingStub returns a fixed rating, its Watch calls fn with values of updates until ctx is done.
+type ratingStub struct {
+	rating  float64
+	err     error
+	updates chan float64
+}
+
+func (s *ratingStub) GetAggregatedRating(context.Context, ratingmodel.RecordID, ratingmodel.RecordType) (float64, error) {
+	return s.rating, s.err
+}
+
+func (s *ratingStub) WatchAggregatedRating(ctx context.Context, _ ratingmodel.RecordID, _ ratingmodel.RecordType, fn func(float64) error) error {
+	for {
+		select {
+		case v := <-s.updates:
+			if err := fn(v); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func rating(v float64) *float64 {
+	return &v
+}
+
+func TestGet(t *testing.T) {
+	metadata := &metadatamodel.Metadata{ID: "1", Title: "A"}
+	tests := []struct {
+		name        string
+		metadata    *metadatamodel.Metadata
+		metadataErr error
+		rating      float64
+		ratingErr   error
+		want        *model.MovieDetails
+		wantErr     error
+	}{
+		{name: "rated", metadata: metadata, rating: 4.5, want: &model.MovieDetails{Metadata: *metadata, Rating: rating(4.5)}},
+		{name: "not rated", metadata: metadata, ratingErr: gateway.ErrNotFound, want: &model.MovieDetails{Metadata: *metadata}},
+		{name: "not found", metadataErr: gateway.ErrNotFound, wantErr: ErrNotFound},
+		{name: "metadata error", metadataErr: errors.New("unexpected error"), wantErr: errors.New("unexpected error")},
+		{name: "rating error", metadata: metadata, ratingErr: errors.New("unexpected error"), wantErr: errors.New("unexpected error")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&ratingStub{rating: tt.rating, err: tt.ratingErr}, &metadataStub{metadata: tt.metadata, err: tt.metadataErr})
+			got, err := c.Get(context.Background(), "1")
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	metadata := &metadataStub{
+		metadata: &metadatamodel.Metadata{ID: "1", Title: "A"},
+		updates:  make(chan *metadatamodel.Metadata),
+		watchErr: make(chan error),
+	}
+	ratings := &ratingStub{rating: 4, updates: make(chan float64)}
+	c := New(ratings, metadata)
+	sent := make(chan *model.MovieDetails)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Watch(ctx, "1", func(d *model.MovieDetails) error {
+			sent <- d
+			return nil
+		})
+	}()
+	assert.Equal(t, &model.MovieDetails{Metadata: metadatamodel.Metadata{ID: "1", Title: "A"}, Rating: rating(4)}, <-sent)
+
+	// Current values sent first by upstream streams don't change the details and aren't sent again.
+	metadata.updates <- &metadatamodel.Metadata{ID: "1", Title: "A"}
+	ratings.updates <- 4
+	metadata.updates <- &metadatamodel.Metadata{ID: "1", Title: "B"}
+	assert.Equal(t, &model.MovieDetails{Metadata: metadatamodel.Metadata{ID: "1", Title: "B"}, Rating: rating(4)}, <-sent)
+	ratings.updates <- 3.5
+	assert.Equal(t, &model.MovieDetails{Metadata: metadatamodel.Metadata{ID: "1", Title: "B"}, Rating: rating(3.5)}, <-sent)
+
+	metadata.watchErr <- gateway.ErrNotFound
+	assert.Equal(t, ErrNotFound, <-errc)
+}
+
+func TestWatchErrors(t *testing.T) {
+	errSend := errors.New("send failed")
+	tests := []struct {
+		name     string
+		metadata *metadataStub
+		sendErr  error
+		wantErr  error
+	}{
+		{name: "not found", metadata: &metadataStub{err: gateway.ErrNotFound}, wantErr: ErrNotFound},
+		{name: "send failed", metadata: &metadataStub{metadata: &metadatamodel.Metadata{ID: "1"}}, sendErr: errSend, wantErr: errSend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&ratingStub{err: gateway.ErrNotFound}, tt.metadata)
+			err := c.Watch(context.Background(), "1", func(*model.MovieDetails) error { return tt.sendErr })
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cached
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
+)
+
+type metadataGateway interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetMany(ctx context.Context, ids []string) (map[string]*model.Metadata, error)
+	Search(ctx context.Context, query string, limit int) ([]*model.Metadata, error)
+	Put(ctx context.Context, m *model.Metadata) error
+	Watch(ctx context.Context, id string, fn func(*model.Metadata) error) error
+}
+
+// MetadataGateway defines a caching decorator for a movie metadata gateway.
+type MetadataGateway struct {
+	next  metadataGateway
+	cache *cache.Cache[*model.Metadata]
+}
+
+// NewMetadataGateway creates a new caching movie metadata gateway. Backend is optional.
+func NewMetadataGateway(next metadataGateway, cfg cache.Config, backend cache.Backend) *MetadataGateway {
+	return &MetadataGateway{next, cache.New[*model.Metadata](cfg, backend, gateway.ErrNotFound)}
+}
+
+// Get returns movie metadata by a movie id. Callers get a copy, so that changing it doesn't affect the cache.
+func (g *MetadataGateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	m, err := g.cache.Get(ctx, "metadata/"+id, func(ctx context.Context) (*model.Metadata, error) {
+		return g.next.Get(ctx, id)
+	})
+	if m == nil {
+		return nil, err
+	}
+	res := *m
+	return &res, err
+}
+
+// GetMany returns metadata of movies by their ids. Batch reads aren't cached.
+func (g *MetadataGateway) GetMany(ctx context.Context, ids []string) (map[string]*model.Metadata, error) {
+	return g.next.GetMany(ctx, ids)
+}
+
+// Search returns metadata of movies matching a query. Search results aren't cached.
+func (g *MetadataGateway) Search(ctx context.Context, query string, limit int) ([]*model.Metadata, error) {
+	return g.next.Search(ctx, query, limit)
+}
+
+// Put writes movie metadata and invalidates its cached copy. Failed invalidations are ignored as entries expire anyway.
+func (g *MetadataGateway) Put(ctx context.Context, m *model.Metadata) error {
+	if err := g.next.Put(ctx, m); err != nil {
+		return err
+	}
+	_ = g.Invalidate(ctx, m.ID)
+	return nil
+}
+
+// Watch calls fn with the metadata of the movie and then again after each change, until ctx is done or fn fails.
+// Changes invalidate the cached metadata, failed invalidations are ignored as entries expire anyway.
+func (g *MetadataGateway) Watch(ctx context.Context, id string, fn func(*model.Metadata) error) error {
+	first := true
+	return g.next.Watch(ctx, id, func(m *model.Metadata) error {
+		if !first {
+			_ = g.Invalidate(ctx, id)
+		}
+		first = false
+		return fn(m)
+	})
+}
+
+// Invalidate removes cached movie metadata for a movie id.
+func (g *MetadataGateway) Invalidate(ctx context.Context, id string) error {
+	return g.cache.Invalidate(ctx, "metadata/"+id)
+}
+
+// OnInvalidate registers a hook called with the cache key each time an entry gets invalidated.
+func (g *MetadataGateway) OnInvalidate(hook func(key string)) {
+	g.cache.OnInvalidate(hook)
+}
+
+// SetConfig changes cache parameters at runtime.
+func (g *MetadataGateway) SetConfig(cfg cache.Config) {
+	g.cache.SetConfig(cfg)
+}
+
+// State returns a snapshot of the cache state.
+func (g *MetadataGateway) State() cache.State {
+	return g.cache.State()
+}
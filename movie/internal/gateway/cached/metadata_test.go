@@ -0,0 +1,69 @@
+package cached
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type metadataStub struct {
+	loads int
+	title string
+}
+
+func (s *metadataStub) Get(_ context.Context, id string) (*model.Metadata, error) {
+	s.loads++
+	title := s.title
+	if title == "" {
+		title = "The Movie"
+	}
+	return &model.Metadata{ID: id, Title: title}, nil
+}
+
+func (s *metadataStub) GetMany(context.Context, []string) (map[string]*model.Metadata, error) {
+	return nil, nil
+}
+
+func (s *metadataStub) Search(context.Context, string, int) ([]*model.Metadata, error) {
+	return nil, nil
+}
+
+func (s *metadataStub) Put(_ context.Context, m *model.Metadata) error {
+	s.title = m.Title
+	return nil
+}
+
+func (s *metadataStub) Watch(context.Context, string, func(*model.Metadata) error) error {
+	return nil
+}
+
+func TestMetadataGatewayCopies(t *testing.T) {
+	next := &metadataStub{}
+	g := NewMetadataGateway(next, cache.Config{Size: 10, TTL: time.Minute}, nil)
+	ctx := context.Background()
+	m, err := g.Get(ctx, "1")
+	assert.NoError(t, err)
+	m.Title = "changed by the caller"
+
+	m, err = g.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "The Movie", m.Title)
+	assert.Equal(t, 1, next.loads)
+}
+
+func TestMetadataGatewayPut(t *testing.T) {
+	next := &metadataStub{}
+	g := NewMetadataGateway(next, cache.Config{Size: 10, TTL: time.Minute}, nil)
+	ctx := context.Background()
+	_, err := g.Get(ctx, "1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, g.Put(ctx, &model.Metadata{ID: "1", Title: "Renamed"}))
+	m, err := g.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", m.Title)
+	assert.Equal(t, 2, next.loads)
+}
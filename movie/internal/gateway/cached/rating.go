@@ -0,0 +1,87 @@
+package cached
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
+	"github.com/mkvy/movies-app/rating/pkg/model"
+)
+
+type ratingGateway interface {
+	GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error)
+	GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error)
+	PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	WatchAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, fn func(float64) error) error
+}
+
+// RatingGateway defines a caching decorator for a rating gateway.
+type RatingGateway struct {
+	next  ratingGateway
+	cache *cache.Cache[float64]
+}
+
+// NewRatingGateway creates a new caching rating gateway. Backend is optional.
+func NewRatingGateway(next ratingGateway, cfg cache.Config, backend cache.Backend) *RatingGateway {
+	return &RatingGateway{next, cache.New[float64](cfg, backend, gateway.ErrNotFound)}
+}
+
+// GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
+func (g *RatingGateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
+	return g.cache.Get(ctx, ratingKey(recordID, recordType), func(ctx context.Context) (float64, error) {
+		return g.next.GetAggregatedRating(ctx, recordID, recordType)
+	})
+}
+
+// GetAggregatedRatings returns aggregated ratings of records, records without ratings are omitted.
+// Batch reads aren't cached.
+func (g *RatingGateway) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error) {
+	return g.next.GetAggregatedRatings(ctx, recordIDs, recordType)
+}
+
+// PutRating writes a rating of a record and invalidates its cached aggregated rating. Failed invalidations
+// are ignored as entries expire anyway.
+func (g *RatingGateway) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	if err := g.next.PutRating(ctx, recordID, recordType, rating); err != nil {
+		return err
+	}
+	_ = g.Invalidate(ctx, recordID, recordType)
+	return nil
+}
+
+// WatchAggregatedRating calls fn with the aggregated rating of a record once it is rated and then again after each
+// change, until ctx is done or fn fails. Changes invalidate the cached rating, failed invalidations are ignored
+// as entries expire anyway.
+func (g *RatingGateway) WatchAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, fn func(float64) error) error {
+	first := true
+	return g.next.WatchAggregatedRating(ctx, recordID, recordType, func(v float64) error {
+		if !first {
+			_ = g.Invalidate(ctx, recordID, recordType)
+		}
+		first = false
+		return fn(v)
+	})
+}
+
+// Invalidate removes a cached aggregated rating for a record.
+func (g *RatingGateway) Invalidate(ctx context.Context, recordID model.RecordID, recordType model.RecordType) error {
+	return g.cache.Invalidate(ctx, ratingKey(recordID, recordType))
+}
+
+// OnInvalidate registers a hook called with the cache key each time an entry gets invalidated.
+func (g *RatingGateway) OnInvalidate(hook func(key string)) {
+	g.cache.OnInvalidate(hook)
+}
+
+// SetConfig changes cache parameters at runtime.
+func (g *RatingGateway) SetConfig(cfg cache.Config) {
+	g.cache.SetConfig(cfg)
+}
+
+// State returns a snapshot of the cache state.
+func (g *RatingGateway) State() cache.State {
+	return g.cache.State()
+}
+
+func ratingKey(recordID model.RecordID, recordType model.RecordType) string {
+	return "rating/" + string(recordType) + "/" + string(recordID)
+}
@@ -0,0 +1,48 @@
+package cached
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/cache"
+	"github.com/mkvy/movies-app/rating/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type ratingStub struct {
+	loads  int
+	rating float64
+}
+
+func (s *ratingStub) GetAggregatedRating(context.Context, model.RecordID, model.RecordType) (float64, error) {
+	s.loads++
+	return s.rating, nil
+}
+
+func (s *ratingStub) GetAggregatedRatings(context.Context, []model.RecordID, model.RecordType) (map[model.RecordID]float64, error) {
+	return nil, nil
+}
+
+func (s *ratingStub) PutRating(_ context.Context, _ model.RecordID, _ model.RecordType, rating *model.Rating) error {
+	s.rating = float64(rating.Value)
+	return nil
+}
+
+func (s *ratingStub) WatchAggregatedRating(context.Context, model.RecordID, model.RecordType, func(float64) error) error {
+	return nil
+}
+
+func TestRatingGatewayPutRating(t *testing.T) {
+	next := &ratingStub{rating: 3}
+	g := NewRatingGateway(next, cache.Config{Size: 10, TTL: time.Minute}, nil)
+	ctx := context.Background()
+	v, err := g.GetAggregatedRating(ctx, "1", model.RecordTypeMovie)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), v)
+
+	assert.NoError(t, g.PutRating(ctx, "1", model.RecordTypeMovie, &model.Rating{UserID: "alice", Value: 5}))
+	v, err = g.GetAggregatedRating(ctx, "1", model.RecordTypeMovie)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), v)
+	assert.Equal(t, 2, next.loads)
+}
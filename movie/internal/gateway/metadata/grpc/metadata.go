@@ -4,38 +4,57 @@ import (
 	"context"
 	"github.com/mkvy/movies-app/gen"
 	"github.com/mkvy/movies-app/internal/grpcutil"
+	"github.com/mkvy/movies-app/internal/hedging"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
 	"github.com/mkvy/movies-app/pkg/discovery"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
+	"sync/atomic"
 )
 
+// DefaultMaxAttempts is the default number of attempts of a request failing with a retryable error.
+const DefaultMaxAttempts = 5
+
 // Gateway defines a movie metadata gRPC gateway.
 type Gateway struct {
-	registry discovery.Registry
+	registry    discovery.Registry
+	hedger      *hedging.Hedger
+	creds       credentials.TransportCredentials
+	maxAttempts atomic.Int32
 }
 
 // New creates a new gRPC gateway for a movie metadata service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// Requests are hedged across instances if hedger is not nil. Plaintext connections are used if creds is nil.
+func New(registry discovery.Registry, hedger *hedging.Hedger, creds credentials.TransportCredentials) *Gateway {
+	g := &Gateway{registry: registry, hedger: hedger, creds: creds}
+	g.maxAttempts.Store(DefaultMaxAttempts)
+	return g
+}
+
+// SetMaxAttempts changes the number of attempts of a request failing with a retryable error at runtime.
+func (g *Gateway) SetMaxAttempts(n int) {
+	if n < 1 {
+		n = 1
+	}
+	g.maxAttempts.Store(int32(n))
 }
 
 // Get returns movie metadata by a movie id.
 func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-	client := gen.NewMetadataServiceClient(conn)
 	var resp *gen.GetMetadataResponse
-	const maxRetries = 5
-	for i := 0; i < maxRetries; i++ {
-		resp, err = client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id})
+	var err error
+	maxAttempts := int(g.maxAttempts.Load())
+	for i := 0; i < maxAttempts; i++ {
+		resp, err = g.getMetadata(ctx, id)
 		if err != nil {
 			if shouldRetry(err) {
 				continue
 			}
+			if status.Code(err) == codes.NotFound {
+				return nil, gateway.ErrNotFound
+			}
 			return nil, err
 		}
 		return model.MetadataFromProto(resp.Metadata), nil
@@ -43,6 +62,106 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	return nil, err
 }
 
+func (g *Gateway) getMetadata(ctx context.Context, id string) (*gen.GetMetadataResponse, error) {
+	addrs, err := grpcutil.ServiceAddresses(ctx, "metadata", g.registry)
+	if err != nil {
+		return nil, err
+	}
+	return hedging.Do(ctx, g.hedger, addrs, func(ctx context.Context, addr string) (*gen.GetMetadataResponse, error) {
+		conn, err := grpcutil.InstanceConnection(addr, g.creds)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return gen.NewMetadataServiceClient(conn).GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id})
+	})
+}
+
+// GetMany returns metadata of the movies with the given ids keyed by id, unknown movies are omitted.
+func (g *Gateway) GetMany(ctx context.Context, ids []string) (map[string]*model.Metadata, error) {
+	addrs, err := grpcutil.ServiceAddresses(ctx, "metadata", g.registry)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hedging.Do(ctx, g.hedger, addrs, func(ctx context.Context, addr string) (*gen.BatchGetMetadataResponse, error) {
+		conn, err := grpcutil.InstanceConnection(addr, g.creds)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return gen.NewMetadataServiceClient(conn).BatchGetMetadata(ctx, &gen.BatchGetMetadataRequest{MovieIds: ids})
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*model.Metadata, len(resp.Metadata))
+	for _, m := range resp.Metadata {
+		res[m.Id] = model.MetadataFromProto(m)
+	}
+	return res, nil
+}
+
+// Search returns up to limit movies matching a query.
+func (g *Gateway) Search(ctx context.Context, query string, limit int) ([]*model.Metadata, error) {
+	addrs, err := grpcutil.ServiceAddresses(ctx, "metadata", g.registry)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hedging.Do(ctx, g.hedger, addrs, func(ctx context.Context, addr string) (*gen.SearchMetadataResponse, error) {
+		conn, err := grpcutil.InstanceConnection(addr, g.creds)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return gen.NewMetadataServiceClient(conn).SearchMetadata(ctx, &gen.SearchMetadataRequest{Query: query, Limit: int32(limit)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.Metadata, 0, len(resp.Metadata))
+	for _, m := range resp.Metadata {
+		res = append(res, model.MetadataFromProto(m))
+	}
+	return res, nil
+}
+
+// Put writes movie metadata. Writes aren't hedged or retried.
+func (g *Gateway) Put(ctx context.Context, m *model.Metadata) error {
+	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry, g.creds)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = gen.NewMetadataServiceClient(conn).PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: model.MetadataToProto(m)})
+	return err
+}
+
+// Watch calls fn with the metadata of the movie and then again after each change, until ctx is done or fn fails.
+// Broken streams are reopened, fn may be called again with unchanged metadata then.
+func (g *Gateway) Watch(ctx context.Context, id string, fn func(*model.Metadata) error) error {
+	return gateway.Resubscribe(ctx, func(ctx context.Context, reset func()) error {
+		conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry, g.creds)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		stream, err := gen.NewMetadataServiceClient(conn).WatchMetadata(ctx, &gen.WatchMetadataRequest{MovieId: id})
+		if err != nil {
+			return err
+		}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			reset()
+			if err := fn(model.MetadataFromProto(resp.Metadata)); err != nil {
+				return &gateway.CallbackError{Err: err}
+			}
+		}
+	})
+}
+
 func shouldRetry(err error) bool {
 	e, ok := status.FromError(err)
 	if !ok {
@@ -2,24 +2,30 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"github.com/mkvy/movies-app/gen"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
 	"github.com/mkvy/movies-app/movie/internal/gateway"
 	"github.com/mkvy/movies-app/pkg/discovery"
-	"log"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
 	"math/rand"
 	"net/http"
+	neturl "net/url"
 )
 
 // Gateway defines an HTTP gateway for a movie metadata service.
 type Gateway struct {
 	registry discovery.Registry
+	client   *http.Client
 }
 
 // New creates a new HTTP gateway for a movie metadata service.
 func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+	return &Gateway{registry, &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}}
 }
 
 // Get gets movie metadata by a movie id.
@@ -28,16 +34,13 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Calling metadata service. Request GET " + url)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	url += "/v1/metadata/" + neturl.PathEscape(id)
+	logging.FromContext(ctx).Debug("Calling metadata service", zap.String("url", url))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-	values := req.URL.Query()
-	values.Add("id", id)
-	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := g.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -47,20 +50,22 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	} else if resp.StatusCode/100 != 2 {
 		return nil, fmt.Errorf("non-2xx response: %v", resp)
 	}
-	var v *model.Metadata
-	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var m gen.Metadata
+	if err := protojson.Unmarshal(b, &m); err != nil {
 		return nil, err
 	}
-	return v, nil
+	return model.MetadataFromProto(&m), nil
 }
 
-// getUrl returns random instance url from service registry.
+// getUrl returns the base url of a random HTTP instance from service registry.
 func getUrl(ctx context.Context, registry discovery.Registry) (string, error) {
-	addrs, err := registry.ServiceAddresses(ctx, "metadata")
-	log.Println(err)
+	instances, err := registry.ServiceInstances(ctx, "metadata", discovery.Filter{Protocol: discovery.ProtocolHTTP})
 	if err != nil {
 		return "", err
 	}
-	url := "http://" + addrs[rand.Intn(len(addrs))] + "/metadata"
-	return url, nil
+	return "http://" + instances[rand.Intn(len(instances))].HostPort, nil
 }
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/metadata/1" {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","title":"The Movie","director":"D"}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	registry := memory.NewRegistry()
+	// The gRPC instance isn't reachable, requests must go to the HTTP instance.
+	assert.NoError(t, registry.RegisterInstance(ctx, discovery.Instance{ID: "metadata-1", ServiceName: "metadata", HostPort: "localhost:1", Protocol: discovery.ProtocolGRPC}))
+	assert.NoError(t, registry.RegisterInstance(ctx, discovery.Instance{
+		ID:          "metadata-1-http",
+		ServiceName: "metadata",
+		HostPort:    strings.TrimPrefix(srv.URL, "http://"),
+		Protocol:    discovery.ProtocolHTTP,
+	}))
+
+	tests := []struct {
+		name    string
+		id      string
+		want    *model.Metadata
+		wantErr error
+	}{
+		{name: "found", id: "1", want: &model.Metadata{ID: "1", Title: "The Movie", Director: "D"}},
+		{name: "not found", id: "2", wantErr: gateway.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				got, err := New(registry).Get(ctx, tt.id)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -4,31 +4,154 @@ import (
 	"context"
 	"github.com/mkvy/movies-app/gen"
 	"github.com/mkvy/movies-app/internal/grpcutil"
+	"github.com/mkvy/movies-app/internal/hedging"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
 	"github.com/mkvy/movies-app/pkg/discovery"
 	"github.com/mkvy/movies-app/rating/pkg/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
 )
 
+// DefaultMaxAttempts is the default number of attempts of a request failing with a retryable error.
+const DefaultMaxAttempts = 5
+
 // Gateway defines an gRPC gateway for a rating service.
 type Gateway struct {
-	registry discovery.Registry
+	registry    discovery.Registry
+	hedger      *hedging.Hedger
+	creds       credentials.TransportCredentials
+	maxAttempts atomic.Int32
 }
 
 // New creates a new gRPC gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// Requests are hedged across instances if hedger is not nil. Plaintext connections are used if creds is nil.
+func New(registry discovery.Registry, hedger *hedging.Hedger, creds credentials.TransportCredentials) *Gateway {
+	g := &Gateway{registry: registry, hedger: hedger, creds: creds}
+	g.maxAttempts.Store(DefaultMaxAttempts)
+	return g
+}
+
+// SetMaxAttempts changes the number of attempts of a request failing with a retryable error at runtime.
+func (g *Gateway) SetMaxAttempts(n int) {
+	if n < 1 {
+		n = 1
+	}
+	g.maxAttempts.Store(int32(n))
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	conn, err := grpcutil.ServiceConnection(ctx, "rating", g.registry)
+	var resp *gen.GetAggregatedRatingResponse
+	var err error
+	maxAttempts := int(g.maxAttempts.Load())
+	for i := 0; i < maxAttempts; i++ {
+		resp, err = g.getAggregatedRating(ctx, recordID, recordType)
+		if err != nil {
+			if shouldRetry(err) {
+				continue
+			}
+			if status.Code(err) == codes.NotFound {
+				return 0, gateway.ErrNotFound
+			}
+			return 0, err
+		}
+		return resp.RatingValue, nil
+	}
+	return 0, err
+}
+
+func (g *Gateway) getAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (*gen.GetAggregatedRatingResponse, error) {
+	addrs, err := grpcutil.ServiceAddresses(ctx, "rating", g.registry)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer conn.Close()
-	client := gen.NewRatingServiceClient(conn)
-	resp, err := client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+	return hedging.Do(ctx, g.hedger, addrs, func(ctx context.Context, addr string) (*gen.GetAggregatedRatingResponse, error) {
+		conn, err := grpcutil.InstanceConnection(addr, g.creds)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return gen.NewRatingServiceClient(conn).GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+	})
+}
+
+// GetAggregatedRatings returns aggregated ratings of records of the type, records without ratings are omitted.
+func (g *Gateway) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error) {
+	addrs, err := grpcutil.ServiceAddresses(ctx, "rating", g.registry)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(recordIDs))
+	for _, id := range recordIDs {
+		ids = append(ids, string(id))
+	}
+	resp, err := hedging.Do(ctx, g.hedger, addrs, func(ctx context.Context, addr string) (*gen.BatchGetAggregatedRatingsResponse, error) {
+		conn, err := grpcutil.InstanceConnection(addr, g.creds)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return gen.NewRatingServiceClient(conn).BatchGetAggregatedRatings(ctx, &gen.BatchGetAggregatedRatingsRequest{RecordIds: ids, RecordType: string(recordType)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[model.RecordID]float64, len(resp.RatingValues))
+	for id, v := range resp.RatingValues {
+		res[model.RecordID(id)] = v
+	}
+	return res, nil
+}
+
+// PutRating writes a rating for a record. The rating service attributes it to the authenticated user
+// if its user id is empty. Writes aren't hedged.
+func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	conn, err := grpcutil.ServiceConnection(ctx, "rating", g.registry, g.creds)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	defer conn.Close()
+	_, err = gen.NewRatingServiceClient(conn).PutRating(ctx, &gen.PutRatingRequest{
+		UserId:      string(rating.UserID),
+		RecordId:    string(recordID),
+		RecordType:  string(recordType),
+		RatingValue: int32(rating.Value),
+	})
+	return err
+}
+
+// WatchAggregatedRating calls fn with the aggregated rating of a record once it is rated and then again after each
+// change, until ctx is done or fn fails. Broken streams are reopened, fn may be called again with an unchanged rating then.
+func (g *Gateway) WatchAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, fn func(float64) error) error {
+	return gateway.Resubscribe(ctx, func(ctx context.Context, reset func()) error {
+		conn, err := grpcutil.ServiceConnection(ctx, "rating", g.registry, g.creds)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		stream, err := gen.NewRatingServiceClient(conn).WatchAggregatedRating(ctx, &gen.WatchAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+		if err != nil {
+			return err
+		}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			reset()
+			if err := fn(resp.RatingValue); err != nil {
+				return &gateway.CallbackError{Err: err}
+			}
+		}
+	})
+}
+
+func shouldRetry(err error) bool {
+	e, ok := status.FromError(err)
+	if !ok {
+		return false
 	}
-	return resp.RatingValue, nil
+	return e.Code() == codes.DeadlineExceeded || e.Code() == codes.ResourceExhausted || e.Code() == codes.Unavailable
 }
@@ -1,25 +1,32 @@
 package http
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"github.com/mkvy/movies-app/gen"
 	"github.com/mkvy/movies-app/movie/internal/gateway"
 	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/logging"
 	"github.com/mkvy/movies-app/rating/pkg/model"
-	"log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
 	"math/rand"
 	"net/http"
+	neturl "net/url"
 )
 
 // Gateway defines an HTTP gateway for a rating service.
 type Gateway struct {
 	registry discovery.Registry
+	client   *http.Client
 }
 
 // New creates a new HTTP gateway for a rating service.
 func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+	return &Gateway{registry, &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}}
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
@@ -28,17 +35,13 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 	if err != nil {
 		return 0, err
 	}
-	log.Printf("Calling rating service. Request GET " + url)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	url += "/v1/ratings/" + neturl.PathEscape(string(recordType)) + "/" + neturl.PathEscape(string(recordID))
+	logging.FromContext(ctx).Debug("Calling rating service", zap.String("method", http.MethodGet), zap.String("url", url))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return 0, err
 	}
-	req = req.WithContext(ctx)
-	values := req.URL.Query()
-	values.Add("id", string(recordID))
-	values.Add("type", fmt.Sprintf("%v", recordType))
-	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := g.client.Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -48,11 +51,15 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 	} else if resp.StatusCode/100 != 2 {
 		return 0, fmt.Errorf("non-2xx response: %v", resp)
 	}
-	var v float64
-	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	var v gen.GetAggregatedRatingResponse
+	if err := protojson.Unmarshal(b, &v); err != nil {
 		return 0, err
 	}
-	return v, nil
+	return v.RatingValue, nil
 }
 
 // PutRating writes a rating.
@@ -61,19 +68,23 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 	if err != nil {
 		return err
 	}
-	log.Printf("Calling rating service. Request: PUT " + url)
-	req, err := http.NewRequest(http.MethodPut, url, nil)
+	url += "/v1/ratings"
+	body, err := protojson.Marshal(&gen.PutRatingRequest{
+		UserId:      string(rating.UserID),
+		RecordId:    string(recordID),
+		RecordType:  string(recordType),
+		RatingValue: int32(rating.Value),
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Debug("Calling rating service", zap.String("method", http.MethodPost), zap.String("url", url))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
-	values := req.URL.Query()
-	values.Add("id", string(recordID))
-	values.Add("type", fmt.Sprintf("%v", recordType))
-	values.Add("userId", string(rating.UserID))
-	values.Add("value", fmt.Sprintf("%v", rating.Value))
-	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -84,12 +95,11 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 	return nil
 }
 
-// getUrl returns random instance url from service registry.
+// getUrl returns the base url of a random HTTP instance from service registry.
 func getUrl(ctx context.Context, registry discovery.Registry) (string, error) {
-	addrs, err := registry.ServiceAddresses(ctx, "rating")
+	instances, err := registry.ServiceInstances(ctx, "rating", discovery.Filter{Protocol: discovery.ProtocolHTTP})
 	if err != nil {
 		return "", err
 	}
-	url := "http://" + addrs[rand.Intn(len(addrs))] + "/rating"
-	return url, nil
+	return "http://" + instances[rand.Intn(len(instances))].HostPort, nil
 }
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/movie/internal/gateway"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/mkvy/movies-app/rating/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAggregatedRating(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/ratings/movie/1" {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ratingValue":4.5}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	registry := memory.NewRegistry()
+	// The gRPC instance isn't reachable, requests must go to the HTTP instance.
+	assert.NoError(t, registry.RegisterInstance(ctx, discovery.Instance{ID: "rating-1", ServiceName: "rating", HostPort: "localhost:1", Protocol: discovery.ProtocolGRPC}))
+	assert.NoError(t, registry.RegisterInstance(ctx, discovery.Instance{
+		ID:          "rating-1-http",
+		ServiceName: "rating",
+		HostPort:    strings.TrimPrefix(srv.URL, "http://"),
+		Protocol:    discovery.ProtocolHTTP,
+	}))
+
+	tests := []struct {
+		name    string
+		id      model.RecordID
+		want    float64
+		wantErr error
+	}{
+		{name: "rated", id: "1", want: 4.5},
+		{name: "not rated", id: "2", wantErr: gateway.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				got, err := New(registry).GetAggregatedRating(ctx, tt.id, model.RecordTypeMovie)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
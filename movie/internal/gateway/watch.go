@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Bounds of the delay before reopening a broken watch stream.
+const (
+	minResubscribeDelay = 100 * time.Millisecond
+	maxResubscribeDelay = 10 * time.Second
+)
+
+// CallbackError wraps errors returned by callbacks of watch streams, they end the watch without resubscribing.
+type CallbackError struct {
+	Err error
+}
+
+func (e *CallbackError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CallbackError) Unwrap() error {
+	return e.Err
+}
+
+// Resubscribe calls subscribe until ctx is done or subscribe fails with an error which can't be fixed by retrying,
+// e.g. one wrapped in CallbackError, so that watch streams broken by failing or restarting instances are reopened,
+// possibly on other instances. Attempts are delayed with exponential backoff, which subscribe resets by calling
+// reset once the stream delivers an update. NotFound status errors are returned as ErrNotFound.
+func Resubscribe(ctx context.Context, subscribe func(ctx context.Context, reset func()) error) error {
+	delay := minResubscribeDelay
+	reset := func() { delay = minResubscribeDelay }
+	for {
+		err := subscribe(ctx, reset)
+		var cbErr *CallbackError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &cbErr):
+			return cbErr.Err
+		case status.Code(err) == codes.NotFound:
+			return ErrNotFound
+		case status.Code(err) == codes.InvalidArgument, status.Code(err) == codes.Unauthenticated,
+			status.Code(err) == codes.PermissionDenied, status.Code(err) == codes.Unimplemented:
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxResubscribeDelay {
+			delay = maxResubscribeDelay
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestResubscribe(t *testing.T) {
+	errCallback := errors.New("client is gone")
+	tests := []struct {
+		name         string
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "callback error",
+			errs:         []error{&CallbackError{Err: errCallback}},
+			wantErr:      errCallback,
+			wantAttempts: 1,
+		},
+		{
+			name:         "not found",
+			errs:         []error{status.Error(codes.NotFound, "not found")},
+			wantErr:      ErrNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried",
+			errs:         []error{status.Error(codes.Unavailable, "shutting down"), errors.New("no instances"), &CallbackError{Err: errCallback}},
+			wantErr:      errCallback,
+			wantAttempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Resubscribe(context.Background(), func(ctx context.Context, reset func()) error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
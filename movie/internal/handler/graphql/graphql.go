@@ -0,0 +1,385 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/graph-gophers/graphql-go"
+	graphqlotel "github.com/graph-gophers/graphql-go/trace/otel"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/internal/dataloader"
+	"github.com/mkvy/movies-app/internal/rest"
+	metadatamodel "github.com/mkvy/movies-app/metadata/pkg/model"
+	"github.com/mkvy/movies-app/pkg/logging"
+	ratingmodel "github.com/mkvy/movies-app/rating/pkg/model"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Path is the path the GraphQL endpoint is served at.
+const Path = "/graphql"
+
+// Query limits.
+const (
+	defaultFirst       = 10
+	defaultSearchFirst = 20
+	maxFirst           = 50
+	maxDepth           = 8
+	maxBodySize        = 1 << 20
+)
+
+//go:embed schema.graphql
+var schema string
+
+type metadataGateway interface {
+	GetMany(ctx context.Context, ids []string) (map[string]*metadatamodel.Metadata, error)
+	Search(ctx context.Context, query string, limit int) ([]*metadatamodel.Metadata, error)
+	Put(ctx context.Context, m *metadatamodel.Metadata) error
+}
+
+type ratingGateway interface {
+	GetAggregatedRatings(ctx context.Context, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error)
+	PutRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, rating *ratingmodel.Rating) error
+}
+
+// Handler defines a GraphQL API handler of the movie service. Metadata and ratings of all movies
+// of a query are loaded with batch requests to the upstream services, each movie at most once.
+// Credentials of requests are passed to the upstream services, which authorize mutations.
+type Handler struct {
+	schema          *graphql.Schema
+	metadataGateway metadataGateway
+	ratingGateway   ratingGateway
+}
+
+// New creates a new GraphQL handler.
+func New(metadataGateway metadataGateway, ratingGateway ratingGateway) *Handler {
+	h := &Handler{metadataGateway: metadataGateway, ratingGateway: ratingGateway}
+	h.schema = graphql.MustParseSchema(schema, &resolver{h},
+		graphql.MaxDepth(maxDepth),
+		// Fields of all movies of a page are resolved concurrently, so that their loads are batched.
+		graphql.MaxParallelism(2*maxFirst),
+		graphql.Tracer(&graphqlotel.Tracer{Tracer: otel.Tracer("github.com/mkvy/movies-app/movie/internal/handler/graphql")}),
+	)
+	return h
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP implements http.Handler, queries are posted as JSON.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method "+req.Method+" is not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var r request
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBodySize)).Decode(&r); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := outgoingContext(req.Context(), req)
+	ctx = context.WithValue(ctx, loadersKey{}, h.newLoaders(ctx))
+	resp := h.schema.Exec(ctx, r.Query, r.OperationName, r.Variables)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.FromContext(ctx).Debug("Failed to write response", zap.Error(err))
+	}
+}
+
+// outgoingContext passes credentials and the request ID of the request to upstream services.
+func outgoingContext(ctx context.Context, req *http.Request) context.Context {
+	for _, h := range []string{auth.AuthorizationHeader, auth.APIKeyHeader} {
+		if v := req.Header.Get(h); v != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, h, v)
+		}
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, logging.RequestIDHeader, id)
+	}
+	return ctx
+}
+
+type loadersKey struct{}
+
+// loaders defines per-request loaders of movie metadata and ratings keyed by movie id.
+type loaders struct {
+	metadata *dataloader.Loader[string, *metadatamodel.Metadata]
+	rating   *dataloader.Loader[string, float64]
+}
+
+func (h *Handler) newLoaders(ctx context.Context) *loaders {
+	return &loaders{
+		metadata: dataloader.New(ctx, h.metadataGateway.GetMany, 0, 0),
+		rating: dataloader.New(ctx, func(ctx context.Context, ids []string) (map[string]float64, error) {
+			recordIDs := make([]ratingmodel.RecordID, 0, len(ids))
+			for _, id := range ids {
+				recordIDs = append(recordIDs, ratingmodel.RecordID(id))
+			}
+			values, err := h.ratingGateway.GetAggregatedRatings(ctx, recordIDs, ratingmodel.RecordTypeMovie)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[string]float64, len(values))
+			for id, v := range values {
+				res[string(id)] = v
+			}
+			return res, nil
+		}, 0, 0),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+type resolver struct {
+	h *Handler
+}
+
+func (r *resolver) Movie(ctx context.Context, args struct{ ID graphql.ID }) (*movieResolver, error) {
+	_, found, err := loadersFrom(ctx).metadata.Load(ctx, string(args.ID))
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &movieResolver{id: string(args.ID)}, nil
+}
+
+func (r *resolver) Movies(args struct {
+	IDs   []graphql.ID
+	After *string
+	First *int32
+}) (*connectionResolver, error) {
+	first, err := pageSize(args.First, defaultFirst)
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	if args.After != nil {
+		if start, err = decodeCursor(*args.After); err != nil {
+			return nil, err
+		}
+		start++
+	}
+	c := &connectionResolver{total: len(args.IDs)}
+	for i := start; i < len(args.IDs) && i < start+first; i++ {
+		c.edges = append(c.edges, &edgeResolver{cursor: encodeCursor(i), node: &movieResolver{id: string(args.IDs[i])}})
+	}
+	c.hasNext = start+first < len(args.IDs)
+	return c, nil
+}
+
+func (r *resolver) Search(ctx context.Context, args struct {
+	Query string
+	First *int32
+}) ([]*movieResolver, error) {
+	first, err := pageSize(args.First, defaultSearchFirst)
+	if err != nil {
+		return nil, err
+	}
+	if args.Query == "" {
+		return nil, resolverError(status.Error(codes.InvalidArgument, "query must not be empty"))
+	}
+	found, err := r.h.metadataGateway.Search(ctx, args.Query, first)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	l := loadersFrom(ctx)
+	res := make([]*movieResolver, 0, len(found))
+	for _, m := range found {
+		l.metadata.Prime(m.ID, m)
+		res = append(res, &movieResolver{id: m.ID})
+	}
+	return res, nil
+}
+
+func (r *resolver) RateMovie(ctx context.Context, args struct {
+	ID     graphql.ID
+	Rating int32
+}) (*movieResolver, error) {
+	if _, ok := auth.Caller(ctx); !ok {
+		return nil, resolverError(status.Error(codes.Unauthenticated, "authentication required"))
+	}
+	id := string(args.ID)
+	if err := r.h.ratingGateway.PutRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie, &ratingmodel.Rating{Value: ratingmodel.RatingValue(args.Rating)}); err != nil {
+		return nil, resolverError(err)
+	}
+	loadersFrom(ctx).rating.Clear(id)
+	return &movieResolver{id: id}, nil
+}
+
+type metadataInput struct {
+	Title       string
+	Description string
+	Director    string
+}
+
+func (r *resolver) UpdateMetadata(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input metadataInput
+}) (*movieResolver, error) {
+	if _, ok := auth.Caller(ctx); !ok {
+		return nil, resolverError(status.Error(codes.Unauthenticated, "authentication required"))
+	}
+	m := &metadatamodel.Metadata{ID: string(args.ID), Title: args.Input.Title, Description: args.Input.Description, Director: args.Input.Director}
+	if err := r.h.metadataGateway.Put(ctx, m); err != nil {
+		return nil, resolverError(err)
+	}
+	l := loadersFrom(ctx)
+	l.metadata.Clear(m.ID)
+	l.metadata.Prime(m.ID, m)
+	return &movieResolver{id: m.ID}, nil
+}
+
+type movieResolver struct {
+	id string
+}
+
+func (m *movieResolver) ID() graphql.ID {
+	return graphql.ID(m.id)
+}
+
+func (m *movieResolver) Metadata(ctx context.Context) (*metadataResolver, error) {
+	md, found, err := loadersFrom(ctx).metadata.Load(ctx, m.id)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &metadataResolver{md}, nil
+}
+
+func (m *movieResolver) Rating(ctx context.Context) (*float64, error) {
+	v, found, err := loadersFrom(ctx).rating.Load(ctx, m.id)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &v, nil
+}
+
+type metadataResolver struct {
+	m *metadatamodel.Metadata
+}
+
+func (m *metadataResolver) Title() string {
+	return m.m.Title
+}
+
+func (m *metadataResolver) Description() string {
+	return m.m.Description
+}
+
+func (m *metadataResolver) Director() string {
+	return m.m.Director
+}
+
+type connectionResolver struct {
+	edges   []*edgeResolver
+	total   int
+	hasNext bool
+}
+
+func (c *connectionResolver) Edges() []*edgeResolver {
+	return c.edges
+}
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	p := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.edges) > 0 {
+		p.endCursor = &c.edges[len(c.edges)-1].cursor
+	}
+	return p
+}
+
+func (c *connectionResolver) TotalCount() int32 {
+	return int32(c.total)
+}
+
+type edgeResolver struct {
+	cursor string
+	node   *movieResolver
+}
+
+func (e *edgeResolver) Cursor() string {
+	return e.cursor
+}
+
+func (e *edgeResolver) Node() *movieResolver {
+	return e.node
+}
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool {
+	return p.hasNext
+}
+
+func (p *pageInfoResolver) EndCursor() *string {
+	return p.endCursor
+}
+
+func pageSize(first *int32, def int) (int, error) {
+	if first == nil {
+		return def, nil
+	}
+	if *first < 0 || *first > maxFirst {
+		return 0, resolverError(status.Errorf(codes.InvalidArgument, "first must be between 0 and %d", maxFirst))
+	}
+	return int(*first), nil
+}
+
+const cursorPrefix = "offset:"
+
+// encodeCursor returns an opaque cursor of a position in a list.
+func encodeCursor(i int) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(i)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.URLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(b), cursorPrefix) {
+		if i, err := strconv.Atoi(strings.TrimPrefix(string(b), cursorPrefix)); err == nil && i >= 0 {
+			return i, nil
+		}
+	}
+	return 0, resolverError(status.Errorf(codes.InvalidArgument, "invalid cursor %q", cursor))
+}
+
+// queryError defines an error reported with the gRPC status code name in its extensions,
+// e.g. {"code": "PERMISSION_DENIED"}.
+type queryError struct {
+	st *status.Status
+}
+
+func resolverError(err error) error {
+	return &queryError{status.Convert(err)}
+}
+
+func (e *queryError) Error() string {
+	return e.st.Message()
+}
+
+// Extensions implements the graphql-go extensions interface.
+func (e *queryError) Extensions() map[string]any {
+	return map[string]any{"code": rest.CodeName(e.st.Code())}
+}
@@ -0,0 +1,197 @@
+package graphql
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/internal/auth"
+	metadatamodel "github.com/mkvy/movies-app/metadata/pkg/model"
+	ratingmodel "github.com/mkvy/movies-app/rating/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeGateways struct {
+	mu       sync.Mutex
+	metadata map[string]*metadatamodel.Metadata
+	ratings  map[ratingmodel.RecordID]float64
+	calls    []string
+	apiKey   string
+}
+
+func (g *fakeGateways) record(call string, ids ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sort.Strings(ids)
+	g.calls = append(g.calls, call+"("+strings.Join(ids, ",")+")")
+}
+
+func (g *fakeGateways) GetMany(_ context.Context, ids []string) (map[string]*metadatamodel.Metadata, error) {
+	g.record("GetMany", ids...)
+	res := map[string]*metadatamodel.Metadata{}
+	for _, id := range ids {
+		if m, ok := g.metadata[id]; ok {
+			res[id] = m
+		}
+	}
+	return res, nil
+}
+
+func (g *fakeGateways) Search(_ context.Context, query string, _ int) ([]*metadatamodel.Metadata, error) {
+	g.record("Search", query)
+	var res []*metadatamodel.Metadata
+	for _, m := range g.metadata {
+		if strings.Contains(m.Title, query) {
+			res = append(res, m)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+func (g *fakeGateways) Put(ctx context.Context, m *metadatamodel.Metadata) error {
+	g.record("Put", m.ID)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if keys := md.Get(auth.APIKeyHeader); len(keys) == 0 || keys[0] != g.apiKey {
+		return status.Error(codes.PermissionDenied, "editor role required")
+	}
+	g.metadata[m.ID] = m
+	return nil
+}
+
+func (g *fakeGateways) GetAggregatedRatings(_ context.Context, recordIDs []ratingmodel.RecordID, _ ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error) {
+	ids := make([]string, 0, len(recordIDs))
+	res := map[ratingmodel.RecordID]float64{}
+	for _, id := range recordIDs {
+		ids = append(ids, string(id))
+		if v, ok := g.ratings[id]; ok {
+			res[id] = v
+		}
+	}
+	g.record("GetAggregatedRatings", ids...)
+	return res, nil
+}
+
+func (g *fakeGateways) PutRating(_ context.Context, recordID ratingmodel.RecordID, _ ratingmodel.RecordType, rating *ratingmodel.Rating) error {
+	g.record("PutRating", string(recordID))
+	g.ratings[recordID] = float64(rating.Value)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		principal    bool
+		authDisabled bool
+		wantBody     string
+		wantCalls    []string
+	}{
+		{
+			name:      "movie",
+			query:     `{ movie(id: "1") { id metadata { title } rating } }`,
+			wantBody:  `{"data":{"movie":{"id":"1","metadata":{"title":"Movie 1"},"rating":4.5}}}`,
+			wantCalls: []string{"GetMany(1)", "GetAggregatedRatings(1)"},
+		},
+		{
+			name:      "unknown movie",
+			query:     `{ movie(id: "unknown") { id } }`,
+			wantBody:  `{"data":{"movie":null}}`,
+			wantCalls: []string{"GetMany(unknown)"},
+		},
+		{
+			name:  "movies batched",
+			query: `{ movies(ids: ["1", "2", "1", "3"], first: 3) { totalCount pageInfo { hasNextPage endCursor } edges { node { id metadata { title } rating } } } }`,
+			wantBody: `{"data":{"movies":{"totalCount":4,"pageInfo":{"hasNextPage":true,"endCursor":"b2Zmc2V0OjI="},"edges":[` +
+				`{"node":{"id":"1","metadata":{"title":"Movie 1"},"rating":4.5}},` +
+				`{"node":{"id":"2","metadata":{"title":"Movie 2"},"rating":null}},` +
+				`{"node":{"id":"1","metadata":{"title":"Movie 1"},"rating":4.5}}]}}}`,
+			wantCalls: []string{"GetAggregatedRatings(1,2)", "GetMany(1,2)"},
+		},
+		{
+			name:      "movies after",
+			query:     `{ movies(ids: ["1", "2", "1", "3"], after: "b2Zmc2V0OjI=") { pageInfo { hasNextPage } edges { node { id metadata { title } } } } }`,
+			wantBody:  `{"data":{"movies":{"pageInfo":{"hasNextPage":false},"edges":[{"node":{"id":"3","metadata":null}}]}}}`,
+			wantCalls: []string{"GetMany(3)"},
+		},
+		{
+			name:      "search primes metadata",
+			query:     `{ search(query: "Movie") { id metadata { title } } }`,
+			wantBody:  `{"data":{"search":[{"id":"1","metadata":{"title":"Movie 1"}},{"id":"2","metadata":{"title":"Movie 2"}}]}}`,
+			wantCalls: []string{"Search(Movie)"},
+		},
+		{
+			name:      "first out of range",
+			query:     `{ search(query: "Movie", first: 100) { id } }`,
+			wantBody:  `{"errors":[{"message":"first must be between 0 and 50","path":["search"],"extensions":{"code":"INVALID_ARGUMENT"}}],"data":null}`,
+			wantCalls: nil,
+		},
+		{
+			name:      "rate unauthenticated",
+			query:     `mutation { rateMovie(id: "2", rating: 5) { rating } }`,
+			wantBody:  `{"errors":[{"message":"authentication required","path":["rateMovie"],"extensions":{"code":"UNAUTHENTICATED"}}],"data":null}`,
+			wantCalls: nil,
+		},
+		{
+			name:      "rate",
+			query:     `mutation { rateMovie(id: "2", rating: 5) { id rating } }`,
+			principal: true,
+			wantBody:  `{"data":{"rateMovie":{"id":"2","rating":5}}}`,
+			wantCalls: []string{"PutRating(2)", "GetAggregatedRatings(2)"},
+		},
+		{
+			name:         "rate with authentication disabled",
+			query:        `mutation { rateMovie(id: "2", rating: 5) { id rating } }`,
+			authDisabled: true,
+			wantBody:     `{"data":{"rateMovie":{"id":"2","rating":5}}}`,
+			wantCalls:    []string{"PutRating(2)", "GetAggregatedRatings(2)"},
+		},
+		{
+			name:      "update metadata",
+			query:     `mutation { updateMetadata(id: "3", input: {title: "Movie 3", description: "", director: "D"}) { metadata { title director } } }`,
+			principal: true,
+			wantBody:  `{"data":{"updateMetadata":{"metadata":{"title":"Movie 3","director":"D"}}}}`,
+			wantCalls: []string{"Put(3)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &fakeGateways{
+				metadata: map[string]*metadatamodel.Metadata{
+					"1": {ID: "1", Title: "Movie 1"},
+					"2": {ID: "2", Title: "Movie 2"},
+				},
+				ratings: map[ratingmodel.RecordID]float64{"1": 4.5},
+				apiKey:  "editor-key",
+			}
+			req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(`{"query":`+quote(tt.query)+`}`))
+			if tt.principal {
+				req.Header.Set(auth.APIKeyHeader, "editor-key")
+				req = req.WithContext(auth.NewContext(req.Context(), auth.Principal{Subject: "user0"}))
+			}
+			var h http.Handler = New(g, g)
+			if tt.authDisabled {
+				a, err := auth.New(auth.Config{})
+				assert.NoError(t, err)
+				h = a.Middleware(h)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+			sort.Strings(tt.wantCalls)
+			sort.Strings(g.calls)
+			assert.Equal(t, tt.wantCalls, g.calls)
+		})
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
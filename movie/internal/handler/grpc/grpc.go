@@ -6,6 +6,7 @@ import (
 	"github.com/mkvy/movies-app/gen"
 	"github.com/mkvy/movies-app/metadata/pkg/model"
 	"github.com/mkvy/movies-app/movie/internal/controller/movie"
+	moviemodel "github.com/mkvy/movies-app/movie/pkg/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -32,10 +33,32 @@ func (h *Handler) GetMovieDetails(ctx context.Context, req *gen.GetMovieDetailsR
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	return &gen.GetMovieDetailsResponse{
-		MovieDetails: &gen.MovieDetails{
-			Metadata: model.MetadataToProto(&m.Metadata),
-			Rating:   *m.Rating,
-		},
-	}, nil
+	return &gen.GetMovieDetailsResponse{MovieDetails: detailsToProto(m)}, nil
+}
+
+// WatchMovieDetails streams movie details and their changes until the client cancels the call.
+func (h *Handler) WatchMovieDetails(req *gen.WatchMovieDetailsRequest, stream gen.MovieService_WatchMovieDetailsServer) error {
+	if req == nil || req.MovieId == "" {
+		return status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	ctx := stream.Context()
+	err := h.ctrl.Watch(ctx, req.MovieId, func(m *moviemodel.MovieDetails) error {
+		return stream.Send(&gen.WatchMovieDetailsResponse{MovieDetails: detailsToProto(m)})
+	})
+	if err != nil && errors.Is(err, movie.ErrNotFound) {
+		return status.Errorf(codes.NotFound, err.Error())
+	} else if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	} else if _, ok := status.FromError(err); !ok {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	return err
+}
+
+func detailsToProto(m *moviemodel.MovieDetails) *gen.MovieDetails {
+	details := &gen.MovieDetails{Metadata: model.MetadataToProto(&m.Metadata)}
+	if m.Rating != nil {
+		details.Rating = *m.Rating
+	}
+	return details
 }
@@ -1,36 +1,27 @@
 package http
 
 import (
-	"encoding/json"
-	"errors"
-	"github.com/mkvy/movies-app/movie/internal/controller/movie"
-	"log"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/rest"
 	"net/http"
 )
 
-// Handler defines a movie handler.
-type Handler struct {
-	ctrl *movie.Controller
-}
-
-// New creates a new movie HTTP handler.
-func New(ctrl *movie.Controller) *Handler {
-	return &Handler{ctrl}
-}
-
-// GetMovieDetails handles GET /movie requests.
-func (h *Handler) GetMovieDetails(w http.ResponseWriter, req *http.Request) {
-	id := req.FormValue("id")
-	details, err := h.ctrl.Get(req.Context(), id)
-	if err != nil && errors.Is(err, movie.ErrNotFound) {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	} else if err != nil {
-		log.Printf("Get error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if err := json.NewEncoder(w).Encode(details); err != nil {
-		log.Printf("Encode error: %v\n", err)
+// Routes returns REST routes of the movie service mapped onto its gRPC methods.
+func Routes(h gen.MovieServiceServer) []rest.Route {
+	return []rest.Route{
+		{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/movies/{movie_id}",
+			RPC:          gen.MovieService_GetMovieDetails_FullMethodName,
+			Call:         rest.Unary(h.GetMovieDetails),
+			ResponseBody: "movie_details",
+		},
+		{
+			Method:       http.MethodGet,
+			Pattern:      "/v1/movies/{movie_id}/watch",
+			RPC:          gen.MovieService_WatchMovieDetails_FullMethodName,
+			Call:         rest.ServerStream[*gen.WatchMovieDetailsResponse](h.WatchMovieDetails),
+			ResponseBody: "movie_details",
+		},
 	}
 }
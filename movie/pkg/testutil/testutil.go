@@ -11,8 +11,8 @@ import (
 
 // NewTestMovieGRPCServer creates a new movie gRPC server to be used in tests.
 func NewTestMovieGRPCServer(registry discovery.Registry) gen.MovieServiceServer {
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
+	metadataGateway := metadatagateway.New(registry, nil, nil)
+	ratingGateway := ratinggateway.New(registry, nil, nil)
 	ctrl := movie.New(ratingGateway, metadataGateway)
 	return grpchandler.New(ctrl)
 }
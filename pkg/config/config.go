@@ -0,0 +1,383 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// redacted replaces values of fields tagged with `secret:"true"` when the configuration is printed.
+const redacted = "<redacted>"
+
+// Validator is implemented by configuration types which can check their own consistency.
+type Validator interface {
+	Validate() error
+}
+
+// Loader loads a configuration by layering, from the lowest to the highest priority:
+// defaults already present in the configuration struct, <dir>/base.yaml, <dir>/<env>.yaml,
+// environment variables and command line flags.
+//
+// Environment variables are named after the service and the YAML path of a setting,
+// e.g. METADATA_API_PORT for api.port of the metadata service. Flags use the YAML path,
+// e.g. -api.port=8081. The configuration directory and environment are set with
+// -config-dir and -env flags or <SERVICE>_CONFIG_DIR and <SERVICE>_ENV variables.
+type Loader struct {
+	name      string
+	prefix    string
+	dir       string
+	env       string
+	print     bool
+	overrides []override
+}
+
+type override struct {
+	path  string
+	value string
+}
+
+// NewLoader creates a new configuration loader for a service, dir is the default configuration directory.
+func NewLoader(serviceName string, dir string) *Loader {
+	prefix := strings.ToUpper(strings.ReplaceAll(serviceName, "-", "_"))
+	l := &Loader{name: serviceName, prefix: prefix, dir: dir}
+	if v, ok := os.LookupEnv(prefix + "_CONFIG_DIR"); ok {
+		l.dir = v
+	}
+	l.env = os.Getenv(prefix + "_ENV")
+	return l
+}
+
+// Load parses command line arguments and loads the configuration into cfg, which must be
+// a pointer to a struct holding default values. The result is validated if cfg implements Validator,
+// unless -print-config is set, so that invalid configurations can be printed.
+func (l *Loader) Load(cfg any, args []string) error {
+	fs := flag.NewFlagSet(l.name, flag.ContinueOnError)
+	fs.StringVar(&l.dir, "config-dir", l.dir, "configuration directory")
+	fs.StringVar(&l.env, "env", l.env, "environment, <config-dir>/<env>.yaml is loaded on top of base.yaml")
+	fs.BoolVar(&l.print, "print-config", false, "print the effective configuration and exit")
+	l.overrides = nil
+	err := walk(reflect.ValueOf(cfg).Elem(), "", func(path string, v reflect.Value) error {
+		usage := fmt.Sprintf("override %s (env %s)", path, l.envName(path))
+		fs.Func(path, usage, func(s string) error {
+			// Check the value only, overrides are applied by Reload.
+			if err := set(reflect.New(v.Type()).Elem(), s); err != nil {
+				return err
+			}
+			l.overrides = append(l.overrides, override{path, s})
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return l.reload(cfg, !l.print)
+}
+
+// Reload loads the configuration files, environment variables and previously parsed flags into cfg
+// and validates the result. cfg is left untouched if loading or validation fails.
+func (l *Loader) Reload(cfg any) error {
+	return l.reload(cfg, true)
+}
+
+func (l *Loader) reload(cfg any, validate bool) error {
+	dst := reflect.ValueOf(cfg)
+	if dst.Kind() != reflect.Pointer || dst.Elem().Kind() != reflect.Struct {
+		return errors.New("configuration must be a pointer to a struct")
+	}
+	// Work on a copy so that a failed load doesn't leave a partially applied configuration.
+	tmp := reflect.New(dst.Elem().Type())
+	tmp.Elem().Set(dst.Elem())
+	for _, f := range l.Files() {
+		if err := loadFile(f, tmp.Interface()); err != nil {
+			return err
+		}
+	}
+	err := walk(tmp.Elem(), "", func(path string, v reflect.Value) error {
+		if s, ok := os.LookupEnv(l.envName(path)); ok {
+			if err := set(v, s); err != nil {
+				return fmt.Errorf("environment variable %s: %w", l.envName(path), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	overrides := map[string]string{}
+	for _, o := range l.overrides {
+		overrides[o.path] = o.value
+	}
+	err = walk(tmp.Elem(), "", func(path string, v reflect.Value) error {
+		if s, ok := overrides[path]; ok {
+			if err := set(v, s); err != nil {
+				return fmt.Errorf("flag -%s: %w", path, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if v, ok := tmp.Interface().(Validator); ok && validate {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	dst.Elem().Set(tmp.Elem())
+	return nil
+}
+
+// Files returns configuration files in the order they are loaded.
+func (l *Loader) Files() []string {
+	files := []string{filepath.Join(l.dir, "base.yaml")}
+	if l.env != "" {
+		files = append(files, filepath.Join(l.dir, l.env+".yaml"))
+	}
+	return files
+}
+
+// PrintRequested reports whether the -print-config flag is set.
+func (l *Loader) PrintRequested() bool {
+	return l.print
+}
+
+// Print writes the configuration as YAML with secret values redacted.
+func Print(w io.Writer, cfg any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	return enc.Encode(Redact(cfg))
+}
+
+// Redact returns a generic representation of the configuration, keyed by YAML names,
+// with values of fields tagged with `secret:"true"` replaced.
+func Redact(cfg any) map[string]any {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	res := map[string]any{}
+	redactStruct(v, res)
+	return res
+}
+
+func redactStruct(v reflect.Value, dst map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline, ok := yamlName(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		switch {
+		case inline && fv.Kind() == reflect.Struct:
+			redactStruct(fv, dst)
+		case f.Tag.Get("secret") == "true":
+			if !fv.IsZero() {
+				dst[name] = redacted
+			} else {
+				dst[name] = ""
+			}
+		case fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Duration(0)):
+			m := map[string]any{}
+			redactStruct(fv, m)
+			dst[name] = m
+		case fv.Type() == reflect.TypeOf(time.Duration(0)):
+			dst[name] = fv.Interface().(time.Duration).String()
+		default:
+			dst[name] = fv.Interface()
+		}
+	}
+}
+
+func loadFile(path string, cfg any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := yaml.NewDecoder(f).Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// walk calls fn for every settable leaf field of a struct with its YAML path.
+func walk(v reflect.Value, prefix string, fn func(path string, v reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline, ok := yamlName(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if inline {
+			path = prefix
+		}
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := walk(fv, path, fn); err != nil {
+				return err
+			}
+		case supported(fv):
+			if err := fn(path, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func yamlName(f reflect.StructField) (name string, inline bool, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if p == "inline" {
+			return "", true, true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], false, true
+	}
+	return strings.ToLower(f.Name), false, true
+}
+
+func supported(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// set parses s into a leaf value.
+func set(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// envName returns the environment variable name for a YAML path, e.g. METADATA_API_ADVERTISE_HOST for api.advertiseHost.
+func (l *Loader) envName(path string) string {
+	var b strings.Builder
+	b.WriteString(l.prefix)
+	b.WriteByte('_')
+	prevLower := false
+	for _, r := range path {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+			prevLower = false
+		case unicode.IsUpper(r) && prevLower:
+			b.WriteByte('_')
+			b.WriteRune(r)
+			prevLower = false
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+			prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+		}
+	}
+	return b.String()
+}
+
+// Errors collects configuration validation errors.
+type Errors []string
+
+// Add adds a validation error, nested Errors are flattened.
+func (e *Errors) Add(err error) {
+	if err == nil {
+		return
+	}
+	if nested, ok := err.(Errors); ok {
+		*e = append(*e, nested...)
+		return
+	}
+	*e = append(*e, err.Error())
+}
+
+// Addf adds a formatted validation error.
+func (e *Errors) Addf(format string, args ...any) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+// Err returns the collected errors as a single error or nil if there are none.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e Errors) Error() string {
+	return strings.Join(e, "; ")
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	API struct {
+		Host          string `yaml:"host"`
+		AdvertiseHost string `yaml:"advertiseHost"`
+		Port          int    `yaml:"port"`
+	} `yaml:"api"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Tags     []string      `yaml:"tags"`
+	Password string        `yaml:"password" secret:"true"`
+}
+
+func (c testConfig) Validate() error {
+	var errs Errors
+	if c.API.Port <= 0 {
+		errs.Addf("api.port must be positive")
+	}
+	if c.Timeout <= 0 {
+		errs.Addf("timeout must be positive")
+	}
+	return errs.Err()
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), []byte("api:\n  host: base\n  port: 8081\ntimeout: 1s\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "prod.yaml"), []byte("api:\n  host: prod\n"), 0o644))
+	t.Setenv("TEST_API_ADVERTISE_HOST", "advertised")
+	t.Setenv("TEST_API_PORT", "9000")
+	t.Setenv("TEST_TAGS", "a, b")
+
+	var cfg testConfig
+	cfg.Password = "default"
+	l := NewLoader("test", dir)
+	assert.NoError(t, l.Load(&cfg, []string{"-env=prod", "-api.port=9001", "-timeout=5s"}))
+	assert.Equal(t, "prod", cfg.API.Host, "environment file")
+	assert.Equal(t, "advertised", cfg.API.AdvertiseHost, "environment variable")
+	assert.Equal(t, 9001, cfg.API.Port, "flag takes precedence")
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, "default", cfg.Password, "default value")
+
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, cfg))
+	assert.Contains(t, buf.String(), "password: <redacted>")
+	assert.NotContains(t, buf.String(), "default")
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), []byte("api:\n  port: 8081\n"), 0o644))
+
+	cfg := testConfig{Password: "unchanged"}
+	err := NewLoader("test", dir).Load(&cfg, []string{"-api.port=-1"})
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{"api.port must be positive", "timeout must be positive"}, errs)
+	assert.Equal(t, testConfig{Password: "unchanged"}, cfg, "configuration is untouched")
+
+	err = NewLoader("test", dir).Load(&cfg, []string{"-env=missing", "-timeout=1s"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	l := NewLoader("test", dir)
+	assert.NoError(t, l.Load(&cfg, []string{"-print-config", "-api.port=-1"}), "not validated when printed")
+	assert.True(t, l.PrintRequested())
+	assert.Equal(t, -1, cfg.API.Port)
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Watcher holds the current configuration of a running service and reloads it when configuration
+// files change or SIGHUP is received. Reloaded configuration is validated and then passed to
+// subscribers, an invalid configuration is rejected and the current one is kept.
+type Watcher[T any] struct {
+	loader   *Loader
+	defaults func() T
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	current T
+	data    []byte
+	subs    []subscription[T]
+}
+
+type subscription[T any] struct {
+	name string
+	fn   func(cfg T) error
+}
+
+// NewWatcher creates a new configuration watcher. cfg is the configuration loaded by the loader,
+// defaults returns the configuration each reload starts from so that removed settings fall back
+// to their default values.
+func NewWatcher[T any](loader *Loader, cfg T, defaults func() T, logger *zap.Logger) *Watcher[T] {
+	data, _ := readFiles(loader.Files())
+	return &Watcher[T]{loader: loader, defaults: defaults, logger: logger, current: cfg, data: data}
+}
+
+// Current returns the current configuration.
+func (w *Watcher[T]) Current() T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Subscribe registers a function applying a changed configuration to a running component.
+// Subscribers are called in the order of registration. If one fails, the previous configuration
+// is applied again to the subscribers already called and the reload is rejected.
+func (w *Watcher[T]) Subscribe(name string, fn func(cfg T) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, subscription[T]{name, fn})
+}
+
+// Reload loads and validates the configuration and applies it to subscribers if it has changed.
+// It reports whether the configuration has changed.
+func (w *Watcher[T]) Reload() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data, err := readFiles(w.loader.Files())
+	if err != nil {
+		w.data = nil
+		return false, err
+	}
+	// Remember rejected contents too, so that they are reported once.
+	w.data = data
+	cfg := w.defaults()
+	if err := w.loader.Reload(&cfg); err != nil {
+		return false, err
+	}
+	if reflect.DeepEqual(cfg, w.current) {
+		return false, nil
+	}
+	for i, s := range w.subs {
+		if err := s.fn(cfg); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if err := w.subs[j].fn(w.current); err != nil {
+					w.logger.Error("Failed to restore configuration", zap.String("subscriber", w.subs[j].name), zap.Error(err))
+				}
+			}
+			return false, fmt.Errorf("apply configuration to %s: %w", s.name, err)
+		}
+	}
+	w.current = cfg
+	return true, nil
+}
+
+// Run checks configuration files for changes every interval and reloads the configuration
+// when they change or SIGHUP is received, until ctx is done. A zero interval disables polling.
+func (w *Watcher[T]) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.logger.Info("Received SIGHUP, reloading configuration")
+		case <-tick:
+			if !w.filesChanged() {
+				continue
+			}
+		}
+		changed, err := w.Reload()
+		switch {
+		case err != nil:
+			w.logger.Error("Rejected configuration reload", zap.Error(err))
+		case changed:
+			w.logger.Info("Applied reloaded configuration", zap.Strings("files", w.loader.Files()))
+		}
+	}
+}
+
+func (w *Watcher[T]) filesChanged() bool {
+	data, err := readFiles(w.loader.Files())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		// Report a missing or unreadable file once.
+		return w.data != nil
+	}
+	return !bytes.Equal(data, w.data)
+}
+
+func readFiles(paths []string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes(), nil
+}
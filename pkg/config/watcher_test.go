@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "base.yaml")
+	write := func(data string) {
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write("api:\n  port: 8081\ntimeout: 1s\n")
+	defaults := func() testConfig {
+		var cfg testConfig
+		cfg.Password = "default"
+		return cfg
+	}
+
+	cfg := defaults()
+	l := NewLoader("test", dir)
+	assert.NoError(t, l.Load(&cfg, nil))
+	w := NewWatcher(l, cfg, defaults, zap.NewNop())
+
+	var applied []time.Duration
+	w.Subscribe("first", func(cfg testConfig) error {
+		applied = append(applied, cfg.Timeout)
+		return nil
+	})
+	failing := false
+	w.Subscribe("second", func(cfg testConfig) error {
+		if failing {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	changed, err := w.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed, "unchanged configuration is not applied")
+
+	write("api:\n  port: 8081\ntimeout: 2s\n")
+	changed, err = w.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 2*time.Second, w.Current().Timeout)
+	assert.Equal(t, []time.Duration{2 * time.Second}, applied)
+
+	write("api:\n  port: -1\ntimeout: 3s\n")
+	_, err = w.Reload()
+	assert.Error(t, err, "invalid configuration is rejected")
+	assert.Equal(t, 8081, w.Current().API.Port)
+	assert.Equal(t, []time.Duration{2 * time.Second}, applied)
+
+	failing = true
+	write("api:\n  port: 8081\ntimeout: 4s\n")
+	_, err = w.Reload()
+	assert.Error(t, err, "configuration failed to apply is rejected")
+	assert.Equal(t, 2*time.Second, w.Current().Timeout)
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 2 * time.Second}, applied, "previous configuration is restored")
+
+	failing = false
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, 10*time.Millisecond)
+	write("api:\n  port: 8081\ntimeout: 5s\n")
+	assert.Eventually(t, func() bool {
+		return w.Current().Timeout == 5*time.Second
+	}, time.Second, 10*time.Millisecond, "changed file is reloaded")
+}
@@ -6,8 +6,21 @@ import (
 	"fmt"
 	consul "github.com/hashicorp/consul/api"
 	"github.com/mkvy/movies-app/pkg/discovery"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// watchRetryInterval defines a pause between failed blocking queries.
+const watchRetryInterval = time.Second
+
+// Service metadata keys used for instance attributes.
+const (
+	metaVersion  = "version"
+	metaZone     = "zone"
+	metaProtocol = "protocol"
 )
 
 // Registry defines a Consul-based service registry.
@@ -28,7 +41,13 @@ func NewRegistry(addr string) (*Registry, error) {
 
 // Register creates a service record in the registry.
 func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
-	parts := strings.Split(hostPort, ":")
+	return r.RegisterInstance(ctx, discovery.Instance{ID: instanceID, ServiceName: serviceName, HostPort: hostPort})
+}
+
+// RegisterInstance creates a service record carrying instance metadata in the registry.
+// Version, zone and protocol are stored as Consul service metadata, weight as passing weight.
+func (r *Registry) RegisterInstance(ctx context.Context, instance discovery.Instance) error {
+	parts := strings.Split(instance.HostPort, ":")
 	if len(parts) != 2 {
 		return errors.New("hostPort must be in a form of <host>:<port>, example: localhost:8081")
 	}
@@ -36,12 +55,28 @@ func (r *Registry) Register(ctx context.Context, instanceID string, serviceName
 	if err != nil {
 		return err
 	}
+	meta := map[string]string{}
+	for k, v := range instance.Meta {
+		meta[k] = v
+	}
+	for k, v := range map[string]string{metaVersion: instance.Version, metaZone: instance.Zone, metaProtocol: instance.Protocol} {
+		if v != "" {
+			meta[k] = v
+		}
+	}
+	var weights *consul.AgentWeights
+	if instance.Weight > 0 {
+		weights = &consul.AgentWeights{Passing: instance.Weight, Warning: 1}
+	}
 	return r.client.Agent().ServiceRegister(&consul.AgentServiceRegistration{
 		Address: parts[0],
-		ID:      instanceID,
-		Name:    serviceName,
+		ID:      instance.ID,
+		Name:    instance.ServiceName,
 		Port:    port,
-		Check:   &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
+		Tags:    instance.Tags,
+		Meta:    meta,
+		Weights: weights,
+		Check:   &consul.AgentServiceCheck{CheckID: instance.ID, TTL: "5s"},
 	})
 }
 
@@ -58,9 +93,24 @@ func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]
 	} else if len(entries) == 0 {
 		return nil, discovery.ErrNotFound
 	}
-	var res []string
+	return addresses(entries), nil
+}
+
+// ServiceInstances returns active instances of the given service matching the filter.
+// Tags are matched by Consul, the rest of the filter is applied to the returned instances.
+func (r *Registry) ServiceInstances(ctx context.Context, serviceName string, filter discovery.Filter) ([]discovery.Instance, error) {
+	entries, _, err := r.client.Health().ServiceMultipleTags(serviceName, filter.Tags, true, (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var res []discovery.Instance
 	for _, e := range entries {
-		res = append(res, fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port))
+		if i := instance(e); filter.Match(i) {
+			res = append(res, i)
+		}
+	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
 	}
 	return res, nil
 }
@@ -69,3 +119,73 @@ func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]
 func (r *Registry) ReportHealthyState(instanceID string, _ string) error {
 	return r.client.Agent().PassTTL(instanceID, "")
 }
+
+// Watch streams the list of addresses of active instances of the given service each time it changes.
+// Changes are tracked with Consul blocking queries.
+func (r *Registry) Watch(ctx context.Context, serviceName string) (<-chan []string, error) {
+	ch := make(chan []string, 1)
+	go func() {
+		defer close(ch)
+		var index uint64
+		var last []string
+		sent := false
+		for {
+			opts := (&consul.QueryOptions{WaitIndex: index}).WithContext(ctx)
+			entries, meta, err := r.client.Health().Service(serviceName, "", true, opts)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(watchRetryInterval):
+				}
+				continue
+			}
+			// Consul may return an index lower than the previous one, in which case the query is reset.
+			if meta.LastIndex < index {
+				index = 0
+			} else {
+				index = meta.LastIndex
+			}
+			addrs := addresses(entries)
+			if sent && slices.Equal(addrs, last) {
+				continue
+			}
+			select {
+			case ch <- addrs:
+			case <-ctx.Done():
+				return
+			}
+			last, sent = addrs, true
+		}
+	}()
+	return ch, nil
+}
+
+func instance(e *consul.ServiceEntry) discovery.Instance {
+	i := discovery.Instance{
+		ID:          e.Service.ID,
+		ServiceName: e.Service.Service,
+		HostPort:    fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port),
+		Version:     e.Service.Meta[metaVersion],
+		Zone:        e.Service.Meta[metaZone],
+		Protocol:    e.Service.Meta[metaProtocol],
+		Weight:      e.Service.Weights.Passing,
+		Tags:        e.Service.Tags,
+		Meta:        map[string]string{},
+	}
+	for k, v := range e.Service.Meta {
+		if k != metaVersion && k != metaZone && k != metaProtocol {
+			i.Meta[k] = v
+		}
+	}
+	return i
+}
+
+func addresses(entries []*consul.ServiceEntry) []string {
+	res := []string{}
+	for _, e := range entries {
+		res = append(res, fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port))
+	}
+	sort.Strings(res)
+	return res
+}
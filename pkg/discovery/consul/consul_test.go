@@ -0,0 +1,243 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	consul "github.com/hashicorp/consul/api"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul serves the agent registration and health endpoints of the Consul HTTP API.
+// Health queries with an index not lower than the current one block until the entries change.
+type fakeConsul struct {
+	mu         sync.Mutex
+	index      uint64
+	entries    []*consul.ServiceEntry
+	changed    chan struct{}
+	registered []consul.AgentServiceRegistration
+}
+
+func newFakeConsul(t *testing.T) (*fakeConsul, *Registry) {
+	f := &fakeConsul{index: 1, changed: make(chan struct{})}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	r, err := NewRegistry(strings.TrimPrefix(srv.URL, "http://"))
+	assert.NoError(t, err)
+	return f, r
+}
+
+func (f *fakeConsul) set(entries ...*consul.ServiceEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = entries
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == "/v1/agent/service/register":
+		var reg consul.AgentServiceRegistration
+		if err := json.NewDecoder(req.Body).Decode(&reg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.registered = append(f.registered, reg)
+		f.mu.Unlock()
+	case strings.HasPrefix(req.URL.Path, "/v1/health/service/"):
+		wait, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+		f.mu.Lock()
+		index, changed := f.index, f.changed
+		f.mu.Unlock()
+		if wait != 0 && wait >= index {
+			select {
+			case <-changed:
+			case <-req.Context().Done():
+				return
+			}
+		}
+		tags := req.URL.Query()["tag"]
+		f.mu.Lock()
+		res := []*consul.ServiceEntry{}
+		for _, e := range f.entries {
+			if hasTags(e.Service.Tags, tags) {
+				res = append(res, e)
+			}
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(res)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func hasTags(tags []string, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, t := range tags {
+			found = found || t == w
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func entry(id string, address string, port int, meta map[string]string, tags ...string) *consul.ServiceEntry {
+	return &consul.ServiceEntry{Service: &consul.AgentService{
+		ID:      id,
+		Service: "metadata",
+		Address: address,
+		Port:    port,
+		Tags:    tags,
+		Meta:    meta,
+		Weights: consul.AgentWeights{Passing: 1, Warning: 1},
+	}}
+}
+
+func TestRegisterInstance(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance discovery.Instance
+		want     consul.AgentServiceRegistration
+		wantErr  bool
+	}{
+		{
+			name: "metadata",
+			instance: discovery.Instance{
+				ID:          "metadata-1",
+				ServiceName: "metadata",
+				HostPort:    "localhost:8081",
+				Version:     "1.1.0",
+				Zone:        "a",
+				Protocol:    discovery.ProtocolGRPC,
+				Weight:      3,
+				Tags:        []string{"canary"},
+				Meta:        map[string]string{"owner": "movies"},
+			},
+			want: consul.AgentServiceRegistration{
+				ID:      "metadata-1",
+				Name:    "metadata",
+				Address: "localhost",
+				Port:    8081,
+				Tags:    []string{"canary"},
+				Meta:    map[string]string{"owner": "movies", metaVersion: "1.1.0", metaZone: "a", metaProtocol: discovery.ProtocolGRPC},
+				Weights: &consul.AgentWeights{Passing: 3, Warning: 1},
+			},
+		},
+		{
+			name:     "no metadata",
+			instance: discovery.Instance{ID: "metadata-2", ServiceName: "metadata", HostPort: "localhost:8082"},
+			want:     consul.AgentServiceRegistration{ID: "metadata-2", Name: "metadata", Address: "localhost", Port: 8082},
+		},
+		{name: "invalid hostPort", instance: discovery.Instance{ID: "metadata-3", HostPort: "localhost"}, wantErr: true},
+		{name: "invalid port", instance: discovery.Instance{ID: "metadata-4", HostPort: "localhost:http"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, r := newFakeConsul(t)
+			err := r.RegisterInstance(context.Background(), tt.instance)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, f.registered)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.Len(t, f.registered, 1) {
+				got := f.registered[0]
+				assert.Equal(t, tt.instance.ID, got.Check.CheckID)
+				got.Check = nil
+				if len(got.Meta) == 0 {
+					got.Meta = nil
+				}
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServiceInstances(t *testing.T) {
+	ctx := context.Background()
+	f, r := newFakeConsul(t)
+	f.set(
+		entry("metadata-0", "10.0.0.1", 8081, map[string]string{metaVersion: "1.0.0", metaProtocol: discovery.ProtocolGRPC}),
+		entry("metadata-1", "10.0.0.2", 8081, map[string]string{metaVersion: "1.1.0", metaZone: "b", metaProtocol: discovery.ProtocolGRPC, "owner": "movies"}, "canary"),
+	)
+	stable := discovery.Instance{
+		ID: "metadata-0", ServiceName: "metadata", HostPort: "10.0.0.1:8081", Version: "1.0.0",
+		Protocol: discovery.ProtocolGRPC, Weight: 1, Meta: map[string]string{},
+	}
+	canary := discovery.Instance{
+		ID: "metadata-1", ServiceName: "metadata", HostPort: "10.0.0.2:8081", Version: "1.1.0", Zone: "b",
+		Protocol: discovery.ProtocolGRPC, Weight: 1, Tags: []string{"canary"}, Meta: map[string]string{"owner": "movies"},
+	}
+
+	tests := []struct {
+		name    string
+		filter  discovery.Filter
+		want    []discovery.Instance
+		wantErr error
+	}{
+		{name: "all", want: []discovery.Instance{stable, canary}},
+		{name: "tag", filter: discovery.Filter{Tags: []string{"canary"}}, want: []discovery.Instance{canary}},
+		{name: "version", filter: discovery.Filter{Version: "1.0.0"}, want: []discovery.Instance{stable}},
+		{name: "no match", filter: discovery.Filter{Version: "2.0.0"}, wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := r.ServiceInstances(ctx, "metadata", tt.filter)
+		assert.Equal(t, tt.want, got, tt.name)
+		assert.Equal(t, tt.wantErr, err, tt.name)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f, r := newFakeConsul(t)
+	f.set(entry("metadata-1", "10.0.0.2", 8081, nil))
+	ch, err := r.Watch(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2:8081"}, receive(t, ch))
+
+	f.set(entry("metadata-1", "10.0.0.2", 8081, nil), entry("metadata-0", "10.0.0.1", 8081, nil))
+	assert.Equal(t, []string{"10.0.0.1:8081", "10.0.0.2:8081"}, receive(t, ch))
+
+	f.set(entry("metadata-0", "10.0.0.1", 8081, map[string]string{metaVersion: "1.0.1"}), entry("metadata-1", "10.0.0.2", 8081, nil))
+	select {
+	case addrs := <-ch:
+		t.Fatalf("unexpected update of unchanged addresses: %v", addrs)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	f.set()
+	assert.Equal(t, []string{}, receive(t, ch))
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "channel is closed")
+	case <-time.After(time.Second):
+		t.Fatal("channel is not closed after cancellation")
+	}
+}
+
+func receive(t *testing.T, ch <-chan []string) []string {
+	select {
+	case addrs := <-ch:
+		return addrs
+	case <-time.After(time.Second):
+		t.Fatal("no addresses received")
+		return nil
+	}
+}
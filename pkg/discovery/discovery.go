@@ -12,12 +12,19 @@ import (
 type Registry interface {
 	// Register creates a service instance record in the registry.
 	Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error
+	// RegisterInstance creates a service instance record carrying instance metadata in the registry.
+	RegisterInstance(ctx context.Context, instance Instance) error
 	// Deregister removes a service instance record from the registry.
 	Deregister(ctx context.Context, instanceID string, serviceName string) error
 	// ServiceAddresses returns the list of addresses of active instances of the given service.
 	ServiceAddresses(ctx context.Context, serviceID string) ([]string, error)
+	// ServiceInstances returns active instances of the given service matching the filter.
+	ServiceInstances(ctx context.Context, serviceName string, filter Filter) ([]Instance, error)
 	// ReportHealthyState is a push mechanism for reporting healthy state to the registry.
 	ReportHealthyState(instanceID string, serviceName string) error
+	// Watch streams the list of addresses of active instances of the given service each time it changes.
+	// The current list is sent first, the channel is closed once ctx is done.
+	Watch(ctx context.Context, serviceName string) (<-chan []string, error)
 }
 
 // ErrNotFound is returned when no service addresses are found.
@@ -28,3 +35,57 @@ var ErrNotFound = errors.New("no service addresses found")
 func GenerateInstanceID(serviceName string) string {
 	return fmt.Sprintf("%s-%d", serviceName, rand.New(rand.NewSource(time.Now().UnixNano())).Int())
 }
+
+// Supported instance protocols.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Instance defines a service instance record.
+type Instance struct {
+	ID          string            `json:"id" yaml:"id"`
+	ServiceName string            `json:"serviceName" yaml:"serviceName"`
+	HostPort    string            `json:"hostPort" yaml:"hostPort"`
+	Version     string            `json:"version,omitempty" yaml:"version,omitempty"`
+	Zone        string            `json:"zone,omitempty" yaml:"zone,omitempty"`
+	Weight      int               `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Protocol    string            `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Tags        []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
+}
+
+// Filter defines criteria for selecting service instances. Empty fields match any instance.
+type Filter struct {
+	// Tags lists tags all of which an instance must have.
+	Tags     []string
+	Version  string
+	Zone     string
+	Protocol string
+}
+
+// Match reports whether the instance satisfies the filter.
+func (f Filter) Match(i Instance) bool {
+	if f.Version != "" && f.Version != i.Version {
+		return false
+	}
+	if f.Zone != "" && f.Zone != i.Zone {
+		return false
+	}
+	if f.Protocol != "" && f.Protocol != i.Protocol {
+		return false
+	}
+	for _, t := range f.Tags {
+		found := false
+		for _, it := range i.Tags {
+			if t == it {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,333 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/miekg/dns"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config defines DNS registry configuration.
+type Config struct {
+	// Resolver is the DNS server address, the first nameserver from /etc/resolv.conf is used if empty.
+	Resolver string `yaml:"resolver"`
+	// Domain is appended to service names, e.g. default.svc.cluster.local.
+	Domain string `yaml:"domain"`
+	// PortName is the SRV service name, instances are looked up as _<portName>._tcp.<service>.<domain>.
+	PortName string `yaml:"portName"`
+	// Ports defines ports used for A record lookups of services without SRV records.
+	Ports map[string]int `yaml:"ports"`
+	// MinTTL and MaxTTL bound the time lookup results are cached for.
+	MinTTL time.Duration `yaml:"minTTL"`
+	MaxTTL time.Duration `yaml:"maxTTL"`
+	// Timeout limits a single DNS query.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Announcer defines a mechanism publishing service instances to DNS, e.g. a cloud DNS API.
+type Announcer interface {
+	Register(ctx context.Context, instance discovery.Instance) error
+	Deregister(ctx context.Context, instanceID string, serviceName string) error
+	ReportHealthyState(instanceID string, serviceName string) error
+}
+
+// Registry defines a service registry resolving service instances through DNS SRV and A records.
+// Lookup results are cached for the record TTL. Instances are managed outside the application,
+// so registration calls are delegated to an optional announcer.
+type Registry struct {
+	cfg       Config
+	client    *dns.Client
+	tcpClient *dns.Client
+	announcer Announcer
+
+	mu    sync.Mutex
+	cache map[string]*lookup
+}
+
+type lookup struct {
+	instances []discovery.Instance
+	err       error
+	expires   time.Time
+}
+
+// NewRegistry creates a new DNS-based service registry. Announcer is optional.
+func NewRegistry(cfg Config, announcer Announcer) (*Registry, error) {
+	if cfg.Resolver == "" {
+		conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, err
+		}
+		if len(conf.Servers) == 0 {
+			return nil, errors.New("no nameservers configured")
+		}
+		cfg.Resolver = net.JoinHostPort(conf.Servers[0], conf.Port)
+	}
+	if cfg.PortName == "" {
+		cfg.PortName = discovery.ProtocolGRPC
+	}
+	if cfg.MinTTL <= 0 {
+		cfg.MinTTL = time.Second
+	}
+	if cfg.MaxTTL < cfg.MinTTL {
+		cfg.MaxTTL = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	return &Registry{
+		cfg:       cfg,
+		client:    &dns.Client{Timeout: cfg.Timeout},
+		tcpClient: &dns.Client{Net: "tcp", Timeout: cfg.Timeout},
+		announcer: announcer,
+		cache:     map[string]*lookup{},
+	}, nil
+}
+
+// Register delegates to the announcer if there is one.
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+	return r.RegisterInstance(ctx, discovery.Instance{ID: instanceID, ServiceName: serviceName, HostPort: hostPort})
+}
+
+// RegisterInstance delegates to the announcer if there is one.
+func (r *Registry) RegisterInstance(ctx context.Context, instance discovery.Instance) error {
+	if r.announcer == nil {
+		return nil
+	}
+	return r.announcer.Register(ctx, instance)
+}
+
+// Deregister delegates to the announcer if there is one.
+func (r *Registry) Deregister(ctx context.Context, instanceID string, serviceName string) error {
+	if r.announcer == nil {
+		return nil
+	}
+	return r.announcer.Deregister(ctx, instanceID, serviceName)
+}
+
+// ReportHealthyState delegates to the announcer if there is one.
+func (r *Registry) ReportHealthyState(instanceID string, serviceName string) error {
+	if r.announcer == nil {
+		return nil
+	}
+	return r.announcer.ReportHealthyState(instanceID, serviceName)
+}
+
+// ServiceAddresses returns the list of addresses of instances of the given service.
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	instances, err := r.ServiceInstances(ctx, serviceName, discovery.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	return addresses(instances), nil
+}
+
+// ServiceInstances returns instances of the given service matching the filter.
+func (r *Registry) ServiceInstances(ctx context.Context, serviceName string, filter discovery.Filter) ([]discovery.Instance, error) {
+	l := r.cached(ctx, serviceName)
+	if l.err != nil {
+		return nil, l.err
+	}
+	var res []discovery.Instance
+	for _, i := range l.instances {
+		if filter.Match(i) {
+			res = append(res, i)
+		}
+	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return res, nil
+}
+
+// Watch streams the list of addresses of the given service instances each time it changes.
+// Records are re-resolved once their TTL expires. Failed lookups keep the last resolved list,
+// so that watchers don't lose all instances on transient DNS errors.
+func (r *Registry) Watch(ctx context.Context, serviceName string) (<-chan []string, error) {
+	ch := make(chan []string, 1)
+	go func() {
+		defer close(ch)
+		var last []string
+		sent := false
+		for {
+			l := r.cached(ctx, serviceName)
+			addrs := addresses(l.instances)
+			if l.err != nil && !errors.Is(l.err, discovery.ErrNotFound) && sent {
+				addrs = last
+			}
+			if !sent || !slices.Equal(addrs, last) {
+				select {
+				case ch <- addrs:
+				case <-ctx.Done():
+					return
+				}
+				last, sent = addrs, true
+			}
+			wait := time.Until(l.expires)
+			if wait < r.cfg.MinTTL {
+				wait = r.cfg.MinTTL
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// cached returns a cached lookup result for the service, resolving it if it is missing or expired.
+// Failed lookups are cached for MinTTL.
+func (r *Registry) cached(ctx context.Context, serviceName string) *lookup {
+	r.mu.Lock()
+	l, ok := r.cache[serviceName]
+	r.mu.Unlock()
+	if ok && time.Now().Before(l.expires) {
+		return l
+	}
+	instances, ttl, err := r.resolve(ctx, serviceName)
+	if err == nil && len(instances) == 0 {
+		err = discovery.ErrNotFound
+	}
+	if err != nil || ttl < r.cfg.MinTTL {
+		ttl = r.cfg.MinTTL
+	} else if ttl > r.cfg.MaxTTL {
+		ttl = r.cfg.MaxTTL
+	}
+	l = &lookup{instances: instances, err: err, expires: time.Now().Add(ttl)}
+	if ctx.Err() == nil {
+		r.mu.Lock()
+		r.cache[serviceName] = l
+		r.mu.Unlock()
+	}
+	return l
+}
+
+// resolve looks up SRV records of the service falling back to A records.
+// It returns the instances found and the lowest TTL of the records involved.
+func (r *Registry) resolve(ctx context.Context, serviceName string) ([]discovery.Instance, time.Duration, error) {
+	name := r.fqdn(serviceName)
+	resp, err := r.query(ctx, "_"+r.cfg.PortName+"._tcp."+name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := r.cfg.MaxTTL
+	extra := map[string][]net.IP{}
+	for _, rr := range resp.Extra {
+		if a, ok := rr.(*dns.A); ok {
+			extra[a.Hdr.Name] = append(extra[a.Hdr.Name], a.A)
+			ttl = minTTL(ttl, a.Hdr.Ttl)
+		}
+	}
+	var res []discovery.Instance
+	for _, rr := range resp.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		ttl = minTTL(ttl, srv.Hdr.Ttl)
+		ips, ok := extra[srv.Target]
+		if !ok {
+			var aTTL time.Duration
+			if ips, aTTL, err = r.lookupA(ctx, srv.Target); err != nil {
+				return nil, 0, err
+			}
+			if aTTL < ttl {
+				ttl = aTTL
+			}
+		}
+		for _, ip := range ips {
+			res = append(res, r.instance(serviceName, ip, int(srv.Port), int(srv.Weight)))
+		}
+	}
+	if len(resp.Answer) > 0 {
+		return res, ttl, nil
+	}
+
+	port, ok := r.cfg.Ports[serviceName]
+	if !ok {
+		return nil, 0, discovery.ErrNotFound
+	}
+	ips, ttl, err := r.lookupA(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, ip := range ips {
+		res = append(res, r.instance(serviceName, ip, port, 0))
+	}
+	return res, ttl, nil
+}
+
+func (r *Registry) lookupA(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	resp, err := r.query(ctx, name, dns.TypeA)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := r.cfg.MaxTTL
+	var res []net.IP
+	for _, rr := range resp.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			res = append(res, a.A)
+			ttl = minTTL(ttl, a.Hdr.Ttl)
+		}
+	}
+	return res, ttl, nil
+}
+
+func (r *Registry) query(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	resp, _, err := r.client.ExchangeContext(ctx, m, r.cfg.Resolver)
+	if err == nil && resp.Truncated {
+		// Large answers, e.g. SRV records of many instances, don't fit into UDP responses.
+		resp, _, err = r.tcpClient.ExchangeContext(ctx, m, r.cfg.Resolver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("dns query %s failed: %s", name, dns.RcodeToString[resp.Rcode])
+	}
+	return resp, nil
+}
+
+func (r *Registry) instance(serviceName string, ip net.IP, port int, weight int) discovery.Instance {
+	hostPort := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	return discovery.Instance{
+		ID:          serviceName + "-" + hostPort,
+		ServiceName: serviceName,
+		HostPort:    hostPort,
+		Weight:      weight,
+		Protocol:    r.cfg.PortName,
+	}
+}
+
+func (r *Registry) fqdn(serviceName string) string {
+	if r.cfg.Domain == "" {
+		return serviceName
+	}
+	return serviceName + "." + r.cfg.Domain
+}
+
+func minTTL(d time.Duration, ttl uint32) time.Duration {
+	if t := time.Duration(ttl) * time.Second; t < d {
+		return t
+	}
+	return d
+}
+
+func addresses(instances []discovery.Instance) []string {
+	res := []string{}
+	for _, i := range instances {
+		res = append(res, i.HostPort)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,150 @@
+package dns
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type server struct {
+	records map[uint16][]string
+	queries int32
+	// failing makes the server answer with SERVFAIL while set.
+	failing int32
+	// truncateUDP makes the server answer UDP queries with empty truncated responses.
+	truncateUDP bool
+}
+
+// startServer starts an in-process DNS server answering with the given records over UDP and TCP.
+func startServer(t *testing.T, s *server) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&s.queries, 1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if atomic.LoadInt32(&s.failing) == 1 {
+			resp.Rcode = dns.RcodeServerFailure
+			_ = w.WriteMsg(resp)
+			return
+		}
+		if s.truncateUDP && w.RemoteAddr().Network() == "udp" {
+			resp.Truncated = true
+			_ = w.WriteMsg(resp)
+			return
+		}
+		q := req.Question[0]
+		for _, record := range s.records[q.Qtype] {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if rr.Header().Name == q.Name {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		if len(resp.Answer) == 0 {
+			resp.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(resp)
+	})
+	udp := &dns.Server{PacketConn: pc, Handler: handler}
+	tcp := &dns.Server{Listener: l, Handler: handler}
+	go func() { _ = udp.ActivateAndServe() }()
+	go func() { _ = tcp.ActivateAndServe() }()
+	t.Cleanup(func() {
+		_ = udp.Shutdown()
+		_ = tcp.Shutdown()
+	})
+	return pc.LocalAddr().String()
+}
+
+func TestServiceInstances(t *testing.T) {
+	srv := &server{records: map[uint16][]string{
+		dns.TypeSRV: {
+			"_grpc._tcp.metadata.default.svc.cluster.local. 30 IN SRV 0 50 8081 metadata-0.metadata.default.svc.cluster.local.",
+			"_grpc._tcp.metadata.default.svc.cluster.local. 30 IN SRV 0 50 8081 metadata-1.metadata.default.svc.cluster.local.",
+		},
+		dns.TypeA: {
+			"metadata-0.metadata.default.svc.cluster.local. 30 IN A 10.0.0.1",
+			"metadata-1.metadata.default.svc.cluster.local. 30 IN A 10.0.0.2",
+			"rating.default.svc.cluster.local. 30 IN A 10.0.1.1",
+		},
+	}}
+	addr := startServer(t, srv)
+	r, err := NewRegistry(Config{
+		Resolver: addr,
+		Domain:   "default.svc.cluster.local",
+		Ports:    map[string]int{"rating": 8082},
+	}, nil)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	addrs, err := r.ServiceAddresses(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:8081", "10.0.0.2:8081"}, addrs)
+	n := atomic.LoadInt32(&srv.queries)
+	_, err = r.ServiceAddresses(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, n, atomic.LoadInt32(&srv.queries), "lookup is cached")
+
+	instances, err := r.ServiceInstances(ctx, "rating", discovery.Filter{Protocol: discovery.ProtocolGRPC})
+	assert.NoError(t, err)
+	assert.Equal(t, []discovery.Instance{{
+		ID:          "rating-10.0.1.1:8082",
+		ServiceName: "rating",
+		HostPort:    "10.0.1.1:8082",
+		Protocol:    discovery.ProtocolGRPC,
+	}}, instances, "A record fallback")
+
+	_, err = r.ServiceAddresses(ctx, "movie")
+	assert.Equal(t, discovery.ErrNotFound, err)
+}
+
+func TestTruncatedResponse(t *testing.T) {
+	addr := startServer(t, &server{records: map[uint16][]string{
+		dns.TypeSRV: {"_grpc._tcp.metadata. 30 IN SRV 0 50 8081 metadata-0."},
+		dns.TypeA:   {"metadata-0. 30 IN A 10.0.0.1"},
+	}, truncateUDP: true})
+	r, err := NewRegistry(Config{Resolver: addr}, nil)
+	assert.NoError(t, err)
+	addrs, err := r.ServiceAddresses(context.Background(), "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:8081"}, addrs, "the lookup is retried over TCP")
+}
+
+func TestWatch(t *testing.T) {
+	srv := &server{records: map[uint16][]string{
+		dns.TypeA: {"movie. 1 IN A 10.0.2.1"},
+	}}
+	addr := startServer(t, srv)
+	r, err := NewRegistry(Config{Resolver: addr, Ports: map[string]int{"movie": 8083}, MinTTL: 10 * time.Millisecond, MaxTTL: 10 * time.Millisecond}, nil)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := r.Watch(ctx, "movie")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.2.1:8083"}, <-ch)
+
+	atomic.StoreInt32(&srv.failing, 1)
+	select {
+	case addrs := <-ch:
+		t.Errorf("unexpected update on lookup errors: %v", addrs)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Greater(t, atomic.LoadInt32(&srv.queries), int32(2), "records are re-resolved")
+	cancel()
+	for range ch {
+	}
+}
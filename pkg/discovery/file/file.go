@@ -0,0 +1,74 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery/static"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+// DefaultReloadInterval defines how often the endpoints file is checked for changes by default.
+const DefaultReloadInterval = 5 * time.Second
+
+// Registry defines a service registry serving instances listed in a YAML or JSON endpoints file.
+// The file is reloaded on change, e.g. when a mounted Kubernetes ConfigMap gets updated.
+// Invalid file contents are ignored and the previously loaded instances are kept.
+type Registry struct {
+	*static.Registry
+	path string
+	data []byte
+}
+
+// NewRegistry creates a new file-based service registry. The file is checked for changes
+// every interval until ctx is done.
+func NewRegistry(ctx context.Context, path string, interval time.Duration) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := static.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	r := &Registry{Registry: static.NewRegistry(cfg), path: path, data: data}
+	go r.watchFile(ctx, interval)
+	return r, nil
+}
+
+func (r *Registry) watchFile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.reload(); err != nil {
+			logging.FromContext(ctx).Error("Failed to reload endpoints file", zap.String("path", r.path), zap.Error(err))
+		}
+	}
+}
+
+func (r *Registry) reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, r.data) {
+		return nil
+	}
+	cfg, err := static.Parse(data)
+	if err != nil {
+		return err
+	}
+	r.data = data
+	r.Update(cfg)
+	return nil
+}
@@ -0,0 +1,54 @@
+package file
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+services:
+  metadata:
+    - hostPort: metadata-0:8081
+      protocol: grpc
+`), 0o644))
+	r, err := NewRegistry(ctx, path, 10*time.Millisecond)
+	assert.NoError(t, err)
+	ch, err := r.Watch(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata-0:8081"}, <-ch)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"services": {"metadata": [
+		{"hostPort": "metadata-0:8081", "protocol": "grpc"},
+		{"hostPort": "metadata-1:8081", "protocol": "grpc", "version": "1.1.0"}
+	]}}`), 0o644))
+	select {
+	case addrs := <-ch:
+		assert.Equal(t, []string{"metadata-0:8081", "metadata-1:8081"}, addrs)
+	case <-time.After(time.Second):
+		t.Fatal("endpoints file change is not picked up")
+	}
+	instances, err := r.ServiceInstances(ctx, "metadata", discovery.Filter{Version: "1.1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []discovery.Instance{{
+		ID:          "metadata-metadata-1:8081",
+		ServiceName: "metadata",
+		HostPort:    "metadata-1:8081",
+		Version:     "1.1.0",
+		Protocol:    discovery.ProtocolGRPC,
+	}}, instances)
+
+	assert.NoError(t, os.WriteFile(path, []byte("services: [invalid"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	addrs, err := r.ServiceAddresses(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata-0:8081", "metadata-1:8081"}, addrs, "invalid file is ignored")
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"github.com/mkvy/movies-app/pkg/discovery"
-	"log"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"go.uber.org/zap"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 )
@@ -13,36 +16,49 @@ import (
 type Registry struct {
 	sync.RWMutex
 	serviceAddrs map[string]map[string]*serviceInstance
+	watchers     map[string]map[chan struct{}]struct{}
 }
 
 type serviceInstance struct {
-	hostPort   string
+	discovery.Instance
 	lastActive time.Time
 }
 
+// healthTTL defines how long an instance stays active after its last reported healthy state.
+const healthTTL = 5 * time.Second
+
 var once sync.Once
-var regMap map[string]map[string]*serviceInstance
+var registry *Registry
 
 // NewRegistry Factory for new in-memory service registry
 // format map[serviceName][instanceID]*serviceInstance.
+// All registries created in a process share the same state.
 func NewRegistry() *Registry {
 	once.Do(func() {
-		regMap = map[string]map[string]*serviceInstance{}
+		registry = &Registry{
+			serviceAddrs: map[string]map[string]*serviceInstance{},
+			watchers:     map[string]map[chan struct{}]struct{}{},
+		}
 	})
-	return &Registry{serviceAddrs: regMap}
+	return registry
 }
 
 // Register creates a service record in the registry.
 func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+	return r.RegisterInstance(ctx, discovery.Instance{ID: instanceID, ServiceName: serviceName, HostPort: hostPort})
+}
+
+// RegisterInstance creates a service record carrying instance metadata in the registry.
+func (r *Registry) RegisterInstance(ctx context.Context, instance discovery.Instance) error {
 	r.Lock()
 	defer r.Unlock()
-	log.Println("registering service instanceID ", instanceID)
-	if _, ok := r.serviceAddrs[serviceName]; !ok {
-		r.serviceAddrs[serviceName] = map[string]*serviceInstance{}
+	logging.FromContext(ctx).Debug("Registering service instance", zap.String("instanceID", instance.ID))
+	if _, ok := r.serviceAddrs[instance.ServiceName]; !ok {
+		r.serviceAddrs[instance.ServiceName] = map[string]*serviceInstance{}
 	}
 
-	r.serviceAddrs[serviceName][instanceID] = &serviceInstance{hostPort: hostPort, lastActive: time.Now()}
-
+	r.serviceAddrs[instance.ServiceName][instance.ID] = &serviceInstance{Instance: instance, lastActive: time.Now()}
+	r.notify(instance.ServiceName)
 	return nil
 }
 
@@ -54,6 +70,7 @@ func (r *Registry) Deregister(ctx context.Context, instanceID string, serviceNam
 		return nil
 	}
 	delete(r.serviceAddrs[serviceName], instanceID)
+	r.notify(serviceName)
 	return nil
 }
 
@@ -68,6 +85,7 @@ func (r *Registry) ReportHealthyState(instanceID string, serviceName string) err
 		return errors.New("service instance is not registered yet")
 	}
 	r.serviceAddrs[serviceName][instanceID].lastActive = time.Now()
+	r.notify(serviceName)
 	return nil
 }
 
@@ -78,12 +96,92 @@ func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]
 	if len(r.serviceAddrs[serviceName]) == 0 {
 		return nil, discovery.ErrNotFound
 	}
-	var res []string
+	return r.activeAddresses(serviceName), nil
+}
+
+// ServiceInstances returns active instances of the given service matching the filter.
+func (r *Registry) ServiceInstances(ctx context.Context, serviceName string, filter discovery.Filter) ([]discovery.Instance, error) {
+	r.RLock()
+	defer r.RUnlock()
+	var res []discovery.Instance
 	for _, i := range r.serviceAddrs[serviceName] {
-		if i.lastActive.Before(time.Now().Add(-5 * time.Second)) {
+		if i.lastActive.Before(time.Now().Add(-healthTTL)) || !filter.Match(i.Instance) {
 			continue
 		}
-		res = append(res, i.hostPort)
+		res = append(res, i.Instance)
 	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].ID < res[b].ID })
 	return res, nil
 }
+
+// Watch streams the list of addresses of active instances of the given service each time it changes.
+// Besides registry updates, instances are periodically checked for an expired healthy state.
+func (r *Registry) Watch(ctx context.Context, serviceName string) (<-chan []string, error) {
+	notifyCh := make(chan struct{}, 1)
+	r.Lock()
+	if _, ok := r.watchers[serviceName]; !ok {
+		r.watchers[serviceName] = map[chan struct{}]struct{}{}
+	}
+	r.watchers[serviceName][notifyCh] = struct{}{}
+	r.Unlock()
+
+	ch := make(chan []string, 1)
+	go func() {
+		defer close(ch)
+		defer func() {
+			r.Lock()
+			delete(r.watchers[serviceName], notifyCh)
+			r.Unlock()
+		}()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var last []string
+		sent := false
+		for {
+			r.RLock()
+			addrs := r.activeAddresses(serviceName)
+			r.RUnlock()
+			if !sent || !slices.Equal(addrs, last) {
+				select {
+				case ch <- addrs:
+				case <-ctx.Done():
+					return
+				}
+				last, sent = addrs, true
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-notifyCh:
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// activeAddresses returns sorted addresses of active instances, the caller must hold the lock.
+func (r *Registry) activeAddresses(serviceName string) []string {
+	res := []string{}
+	for _, i := range r.serviceAddrs[serviceName] {
+		if i.lastActive.Before(time.Now().Add(-healthTTL)) {
+			continue
+		}
+		res = append(res, i.HostPort)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// notify wakes up watchers of the given service, the caller must hold the lock.
+func (r *Registry) notify(serviceName string) {
+	for ch := range r.watchers[serviceName] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRegistry()
+	ch, err := r.Watch(ctx, "watched")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, receive(t, ch))
+
+	assert.NoError(t, r.Register(ctx, "watched-1", "watched", "localhost:8081"))
+	assert.Equal(t, []string{"localhost:8081"}, receive(t, ch))
+	assert.NoError(t, r.Register(ctx, "watched-2", "watched", "localhost:8082"))
+	assert.Equal(t, []string{"localhost:8081", "localhost:8082"}, receive(t, ch))
+	assert.NoError(t, r.Deregister(ctx, "watched-1", "watched"))
+	assert.Equal(t, []string{"localhost:8082"}, receive(t, ch))
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "channel is closed")
+	case <-time.After(time.Second):
+		t.Fatal("channel is not closed after cancellation")
+	}
+}
+
+func receive(t *testing.T, ch <-chan []string) []string {
+	select {
+	case addrs := <-ch:
+		return addrs
+	case <-time.After(time.Second):
+		t.Fatal("no addresses received")
+		return nil
+	}
+}
+
+func TestServiceInstances(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry()
+	stable := discovery.Instance{ID: "filtered-1", ServiceName: "filtered", HostPort: "localhost:8081", Version: "1.0.0", Protocol: discovery.ProtocolGRPC}
+	canary := discovery.Instance{ID: "filtered-2", ServiceName: "filtered", HostPort: "localhost:8082", Version: "1.1.0", Protocol: discovery.ProtocolGRPC, Tags: []string{"canary"}}
+	web := discovery.Instance{ID: "filtered-3", ServiceName: "filtered", HostPort: "localhost:9081", Version: "1.0.0", Protocol: discovery.ProtocolHTTP}
+	for _, i := range []discovery.Instance{stable, canary, web} {
+		assert.NoError(t, r.RegisterInstance(ctx, i))
+	}
+
+	tests := []struct {
+		name    string
+		filter  discovery.Filter
+		want    []discovery.Instance
+		wantErr error
+	}{
+		{name: "all", want: []discovery.Instance{stable, canary, web}},
+		{name: "tag", filter: discovery.Filter{Tags: []string{"canary"}}, want: []discovery.Instance{canary}},
+		{name: "version", filter: discovery.Filter{Version: "1.0.0"}, want: []discovery.Instance{stable, web}},
+		{name: "protocol", filter: discovery.Filter{Protocol: discovery.ProtocolHTTP}, want: []discovery.Instance{web}},
+		{name: "no match", filter: discovery.Filter{Version: "2.0.0"}, wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := r.ServiceInstances(ctx, "filtered", tt.filter)
+		assert.Equal(t, tt.want, got, tt.name)
+		assert.Equal(t, tt.wantErr, err, tt.name)
+	}
+}
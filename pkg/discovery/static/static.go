@@ -0,0 +1,179 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"gopkg.in/yaml.v3"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Config defines a static list of service instances, keyed by service name.
+type Config struct {
+	Services map[string][]discovery.Instance `yaml:"services" json:"services"`
+}
+
+// Parse parses a YAML or JSON encoded registry configuration.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	for name, instances := range cfg.Services {
+		for i := range instances {
+			if instances[i].HostPort == "" {
+				return Config{}, errors.New("service " + name + " has an instance without hostPort")
+			}
+			instances[i].ServiceName = name
+			if instances[i].ID == "" {
+				instances[i].ID = name + "-" + instances[i].HostPort
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// Registry defines a service registry serving a static list of instances.
+// Registration and health reporting are no-ops as instances are managed outside the application.
+type Registry struct {
+	sync.RWMutex
+	services map[string][]discovery.Instance
+	watchers map[string]map[chan struct{}]struct{}
+}
+
+// NewRegistry creates a new static service registry.
+func NewRegistry(cfg Config) *Registry {
+	r := &Registry{watchers: map[string]map[chan struct{}]struct{}{}}
+	r.Update(cfg)
+	return r
+}
+
+// NewRegistryFromFile creates a new static service registry from a YAML or JSON file.
+func NewRegistryFromFile(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(cfg), nil
+}
+
+// Update replaces the list of instances and notifies watchers.
+func (r *Registry) Update(cfg Config) {
+	services := map[string][]discovery.Instance{}
+	for name, instances := range cfg.Services {
+		services[name] = append([]discovery.Instance(nil), instances...)
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.services = services
+	for _, watchers := range r.watchers {
+		for ch := range watchers {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Register is a no-op for a static registry.
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+	return nil
+}
+
+// RegisterInstance is a no-op for a static registry.
+func (r *Registry) RegisterInstance(ctx context.Context, instance discovery.Instance) error {
+	return nil
+}
+
+// Deregister is a no-op for a static registry.
+func (r *Registry) Deregister(ctx context.Context, instanceID string, serviceName string) error {
+	return nil
+}
+
+// ReportHealthyState is a no-op for a static registry.
+func (r *Registry) ReportHealthyState(instanceID string, serviceName string) error {
+	return nil
+}
+
+// ServiceAddresses returns the list of addresses of the given service instances.
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	addrs := r.addresses(serviceName)
+	if len(addrs) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return addrs, nil
+}
+
+// ServiceInstances returns instances of the given service matching the filter.
+func (r *Registry) ServiceInstances(ctx context.Context, serviceName string, filter discovery.Filter) ([]discovery.Instance, error) {
+	r.RLock()
+	defer r.RUnlock()
+	var res []discovery.Instance
+	for _, i := range r.services[serviceName] {
+		if filter.Match(i) {
+			res = append(res, i)
+		}
+	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return res, nil
+}
+
+// Watch streams the list of addresses of the given service instances each time it gets updated.
+func (r *Registry) Watch(ctx context.Context, serviceName string) (<-chan []string, error) {
+	notifyCh := make(chan struct{}, 1)
+	r.Lock()
+	if _, ok := r.watchers[serviceName]; !ok {
+		r.watchers[serviceName] = map[chan struct{}]struct{}{}
+	}
+	r.watchers[serviceName][notifyCh] = struct{}{}
+	r.Unlock()
+
+	ch := make(chan []string, 1)
+	go func() {
+		defer close(ch)
+		defer func() {
+			r.Lock()
+			delete(r.watchers[serviceName], notifyCh)
+			r.Unlock()
+		}()
+		var last []string
+		sent := false
+		for {
+			if addrs := r.addresses(serviceName); !sent || !slices.Equal(addrs, last) {
+				select {
+				case ch <- addrs:
+				case <-ctx.Done():
+					return
+				}
+				last, sent = addrs, true
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-notifyCh:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (r *Registry) addresses(serviceName string) []string {
+	r.RLock()
+	defer r.RUnlock()
+	res := []string{}
+	for _, i := range r.services[serviceName] {
+		res = append(res, i.HostPort)
+	}
+	sort.Strings(res)
+	return res
+}
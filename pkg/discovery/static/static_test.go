@@ -0,0 +1,139 @@
+package static
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			data: `
+services:
+  metadata:
+    - hostPort: metadata-0:8081
+      version: 1.0.0
+      protocol: grpc
+      zone: a
+      tags: [canary]
+      meta:
+        owner: movies
+`,
+			want: Config{Services: map[string][]discovery.Instance{"metadata": {{
+				ID:          "metadata-metadata-0:8081",
+				ServiceName: "metadata",
+				HostPort:    "metadata-0:8081",
+				Version:     "1.0.0",
+				Protocol:    discovery.ProtocolGRPC,
+				Zone:        "a",
+				Tags:        []string{"canary"},
+				Meta:        map[string]string{"owner": "movies"},
+			}}}},
+		},
+		{
+			name: "json with id",
+			data: `{"services": {"rating": [{"id": "rating-0", "hostPort": "rating-0:8082", "weight": 2}]}}`,
+			want: Config{Services: map[string][]discovery.Instance{"rating": {{
+				ID:          "rating-0",
+				ServiceName: "rating",
+				HostPort:    "rating-0:8082",
+				Weight:      2,
+			}}}},
+		},
+		{name: "missing hostPort", data: `{"services": {"rating": [{"id": "rating-0"}]}}`, wantErr: true},
+		{name: "invalid", data: "services: [invalid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServiceInstances(t *testing.T) {
+	ctx := context.Background()
+	stable := discovery.Instance{ID: "metadata-0", ServiceName: "metadata", HostPort: "metadata-0:8081", Version: "1.0.0", Protocol: discovery.ProtocolGRPC}
+	canary := discovery.Instance{ID: "metadata-1", ServiceName: "metadata", HostPort: "metadata-1:8081", Version: "1.1.0", Protocol: discovery.ProtocolGRPC, Tags: []string{"canary"}}
+	r := NewRegistry(Config{Services: map[string][]discovery.Instance{"metadata": {canary, stable}}})
+
+	tests := []struct {
+		name    string
+		service string
+		filter  discovery.Filter
+		want    []discovery.Instance
+		wantErr error
+	}{
+		{name: "all", service: "metadata", want: []discovery.Instance{canary, stable}},
+		{name: "tag", service: "metadata", filter: discovery.Filter{Tags: []string{"canary"}}, want: []discovery.Instance{canary}},
+		{name: "version", service: "metadata", filter: discovery.Filter{Version: "1.0.0"}, want: []discovery.Instance{stable}},
+		{name: "no match", service: "metadata", filter: discovery.Filter{Version: "2.0.0"}, wantErr: discovery.ErrNotFound},
+		{name: "unknown service", service: "rating", wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := r.ServiceInstances(ctx, tt.service, tt.filter)
+		assert.Equal(t, tt.want, got, tt.name)
+		assert.Equal(t, tt.wantErr, err, tt.name)
+	}
+
+	addrs, err := r.ServiceAddresses(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata-0:8081", "metadata-1:8081"}, addrs, "addresses are sorted")
+	_, err = r.ServiceAddresses(ctx, "rating")
+	assert.Equal(t, discovery.ErrNotFound, err)
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	instance := func(hostPort string) discovery.Instance {
+		return discovery.Instance{ID: hostPort, ServiceName: "metadata", HostPort: hostPort}
+	}
+	r := NewRegistry(Config{Services: map[string][]discovery.Instance{"metadata": {instance("metadata-1:8081")}}})
+	ch, err := r.Watch(ctx, "metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata-1:8081"}, receive(t, ch))
+
+	r.Update(Config{Services: map[string][]discovery.Instance{"metadata": {instance("metadata-1:8081"), instance("metadata-0:8081")}}})
+	assert.Equal(t, []string{"metadata-0:8081", "metadata-1:8081"}, receive(t, ch))
+
+	r.Update(Config{Services: map[string][]discovery.Instance{
+		"metadata": {instance("metadata-0:8081"), instance("metadata-1:8081")},
+		"rating":   {instance("rating-0:8082")},
+	}})
+	select {
+	case addrs := <-ch:
+		t.Fatalf("unexpected update of unchanged addresses: %v", addrs)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	r.Update(Config{})
+	assert.Equal(t, []string{}, receive(t, ch))
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "channel is closed")
+	case <-time.After(time.Second):
+		t.Fatal("channel is not closed after cancellation")
+	}
+}
+
+func receive(t *testing.T, ch <-chan []string) []string {
+	select {
+	case addrs := <-ch:
+		return addrs
+	case <-time.After(time.Second):
+		t.Fatal("no addresses received")
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+)
+
+// Supported log formats.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// RequestIDHeader is the metadata key and HTTP header carrying a caller-provided request ID.
+const RequestIDHeader = "x-request-id"
+
+// Field keys added to request-scoped loggers.
+const (
+	ServiceKey   = "service"
+	MethodKey    = "method"
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+// Config defines logging configuration.
+type Config struct {
+	// Level is the minimum enabled log level, e.g. debug, info, warn or error. It can be changed at runtime.
+	Level string `yaml:"level"`
+	// Format is json or console, json if empty.
+	Format string `yaml:"format"`
+}
+
+// Validate checks the logging configuration consistency.
+func (c Config) Validate() error {
+	if _, err := zapcore.ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("level: %w", err)
+	}
+	switch c.Format {
+	case "", FormatJSON, FormatConsole:
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q, must be %s or %s", c.Format, FormatJSON, FormatConsole)
+}
+
+// New creates a logger of the service with the configured format. Its level can be changed
+// at runtime with the returned atomic level.
+func New(cfg Config, serviceName string) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, level, err
+	}
+	zapCfg := zap.NewProductionConfig()
+	if cfg.Format == FormatConsole {
+		zapCfg = zap.NewDevelopmentConfig()
+	}
+	zapCfg.Level = level
+	logger, err := zapCfg.Build()
+	if err != nil {
+		return nil, level, err
+	}
+	return logger.With(zap.String(ServiceKey, serviceName)), level, nil
+}
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// NewContext returns a context carrying the logger.
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger of the context, or the global zap logger if there is none,
+// with trace_id and span_id fields of the current span.
+func FromContext(ctx context.Context) *zap.Logger {
+	logger, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		logger = zap.L()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With(zap.String(TraceIDKey, sc.TraceID().String()), zap.String(SpanIDKey, sc.SpanID().String()))
+	}
+	return logger
+}
+
+// RequestID returns the request ID passed by the caller or, if there is none, the trace ID of the request.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// withRequest returns a context carrying the request ID and a logger with method and request_id fields.
+func withRequest(ctx context.Context, logger *zap.Logger, method string, requestID string) context.Context {
+	if requestID == "" {
+		requestID = RequestID(ctx)
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	return NewContext(ctx, logger.With(zap.String(MethodKey, method), zap.String(RequestIDKey, requestID)))
+}
+
+// UnaryServerInterceptor returns an interceptor putting a request-scoped logger derived from logger
+// into the request context. It must run after the tracing interceptor.
+func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequest(ctx, logger, info.FullMethod, ""), req)
+	}
+}
+
+// StreamServerInterceptor returns a stream interceptor putting a request-scoped logger derived from logger
+// into the stream context. It must run after the tracing interceptor.
+func StreamServerInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ss, withRequest(ss.Context(), logger, info.FullMethod, "")})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Middleware returns an HTTP middleware putting a request-scoped logger derived from logger
+// into the request context. It must run after the tracing middleware.
+func Middleware(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := withRequest(req.Context(), logger, req.Method+" "+req.URL.Path, req.Header.Get(RequestIDHeader))
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var spanCtx = trace.NewSpanContext(trace.SpanContextConfig{
+	TraceID:    trace.TraceID{1},
+	SpanID:     trace.SpanID{2},
+	TraceFlags: trace.FlagsSampled,
+})
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default format", cfg: Config{Level: "info"}},
+		{name: "console", cfg: Config{Level: "debug", Format: FormatConsole}},
+		{name: "invalid level", cfg: Config{Level: "verbose"}, wantErr: true},
+		{name: "invalid format", cfg: Config{Level: "info", Format: "logfmt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.cfg.Validate() != nil)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	traced := trace.ContextWithSpanContext(context.Background(), spanCtx)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "none", ctx: context.Background(), want: ""},
+		{name: "trace id", ctx: traced, want: spanCtx.TraceID().String()},
+		{
+			name: "metadata over trace id",
+			ctx:  metadata.NewIncomingContext(traced, metadata.Pairs(RequestIDHeader, "req-1")),
+			want: "req-1",
+		},
+		{
+			name: "context value over metadata",
+			ctx:  withRequest(metadata.NewIncomingContext(traced, metadata.Pairs(RequestIDHeader, "req-1")), zap.NewNop(), "m", "req-2"),
+			want: "req-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RequestID(tt.ctx))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := trace.ContextWithSpanContext(req.Context(), spanCtx)
+		FromContext(ctx).Info("handled")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/metadata", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, map[string]any{
+			MethodKey:    "GET /metadata",
+			RequestIDKey: "req-1",
+			TraceIDKey:   spanCtx.TraceID().String(),
+			SpanIDKey:    spanCtx.SpanID().String(),
+		}, entries[0].ContextMap())
+	}
+}
@@ -0,0 +1,800 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/admin"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/internal/authz"
+	"github.com/mkvy/movies-app/internal/discoveryutil"
+	"github.com/mkvy/movies-app/internal/health"
+	"github.com/mkvy/movies-app/internal/metrics"
+	"github.com/mkvy/movies-app/internal/openapi"
+	"github.com/mkvy/movies-app/internal/ratelimit"
+	"github.com/mkvy/movies-app/internal/rest"
+	"github.com/mkvy/movies-app/internal/tlsutil"
+	"github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"github.com/mkvy/movies-app/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config defines configuration shared by all services.
+type Config struct {
+	API       APIConfig            `yaml:"api"`
+	HTTP      HTTPConfig           `yaml:"http"`
+	Admin     AdminConfig          `yaml:"admin"`
+	Tracing   tracing.Config       `yaml:"tracing"`
+	Registry  discoveryutil.Config `yaml:"registry"`
+	Lifecycle LifecycleConfig      `yaml:"lifecycle"`
+	Log       logging.Config       `yaml:"log"`
+	RateLimit ratelimit.Config     `yaml:"rateLimit"`
+	Auth      auth.Config          `yaml:"auth"`
+	Authz     authz.Config         `yaml:"authz"`
+	TLS       tlsutil.Config       `yaml:"tls"`
+}
+
+// APIConfig defines gRPC API configuration.
+type APIConfig struct {
+	// Host is the address the gRPC server listens on.
+	Host string `yaml:"host"`
+	// AdvertiseHost is the host registered in the service registry, Host is used if empty.
+	AdvertiseHost string `yaml:"advertiseHost"`
+	Port          int    `yaml:"port"`
+}
+
+// HTTPConfig defines configuration of the HTTP server serving metrics, liveness and readiness probes
+// and handlers registered with Service.Handle. The server listens on the API host.
+type HTTPConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// AdminConfig defines configuration of the admin server serving pprof, expvar, build info,
+// the effective configuration, instance state and the log level toggle. The admin server is
+// unauthenticated, so it listens on a separate port of a host which should not be publicly reachable.
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+}
+
+// LifecycleConfig defines service lifecycle timings.
+type LifecycleConfig struct {
+	// HeartbeatInterval defines how often readiness checks run, the healthy state is only reported
+	// to the registry while the instance is ready.
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+	// CheckTimeout bounds the time each readiness check may take.
+	CheckTimeout    time.Duration `yaml:"checkTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// ReloadInterval defines how often configuration files are checked for changes, zero disables
+	// the check and the configuration is only reloaded on SIGHUP.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+// DefaultConfig returns the configuration used unless overridden by configuration files,
+// environment variables or flags.
+func DefaultConfig() Config {
+	return Config{
+		API:   APIConfig{Host: "localhost"},
+		Admin: AdminConfig{Host: "localhost"},
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterOTLPGRPC,
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			Sampler:     tracing.SamplerParentBasedAlwaysOn,
+			Propagators: []string{tracing.PropagatorTraceContext, tracing.PropagatorBaggage},
+		},
+		Registry: discoveryutil.Config{Type: discoveryutil.TypeConsul, Address: "localhost:8500"},
+		Lifecycle: LifecycleConfig{
+			HeartbeatInterval: 1 * time.Second,
+			CheckTimeout:      2 * time.Second,
+			ShutdownTimeout:   10 * time.Second,
+			ReloadInterval:    10 * time.Second,
+		},
+		Log: logging.Config{Level: "info", Format: logging.FormatJSON},
+	}
+}
+
+// Validate checks the configuration consistency.
+func (c Config) Validate() error {
+	var errs config.Errors
+	if c.API.Port < 0 || c.API.Port > 65535 {
+		errs.Addf("api.port must be between 0 and 65535, got %d", c.API.Port)
+	}
+	if c.HTTP.Enabled && (c.HTTP.Port < 0 || c.HTTP.Port > 65535) {
+		errs.Addf("http.port must be between 0 and 65535, got %d", c.HTTP.Port)
+	}
+	if c.Admin.Enabled {
+		if c.Admin.Port < 0 || c.Admin.Port > 65535 {
+			errs.Addf("admin.port must be between 0 and 65535, got %d", c.Admin.Port)
+		}
+		if c.Admin.Port != 0 && (c.Admin.Port == c.API.Port || c.HTTP.Enabled && c.Admin.Port == c.HTTP.Port) {
+			errs.Addf("admin.port must differ from api.port and http.port")
+		}
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs.Addf("tracing: %v", err)
+	}
+	if err := c.Registry.Validate(); err != nil {
+		errs.Addf("registry: %v", err)
+	}
+	if c.Lifecycle.HeartbeatInterval <= 0 {
+		errs.Addf("lifecycle.heartbeatInterval must be positive")
+	}
+	if c.Lifecycle.CheckTimeout <= 0 {
+		errs.Addf("lifecycle.checkTimeout must be positive")
+	}
+	if c.Lifecycle.ShutdownTimeout <= 0 {
+		errs.Addf("lifecycle.shutdownTimeout must be positive")
+	}
+	if c.Lifecycle.ReloadInterval < 0 {
+		errs.Addf("lifecycle.reloadInterval must not be negative")
+	}
+	if err := c.Log.Validate(); err != nil {
+		errs.Addf("log: %v", err)
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		errs.Addf("rateLimit: %v", err)
+	}
+	if err := c.Auth.Validate(); err != nil {
+		errs.Addf("auth: %v", err)
+	}
+	if err := c.Authz.Validate(); err != nil {
+		errs.Addf("authz: %v", err)
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs.Addf("tls: %v", err)
+	}
+	return errs.Err()
+}
+
+// Option defines a service option.
+type Option func(*Service)
+
+// WithLogger sets the service logger, a logger with the configured level and format is used by default.
+// The log level of a logger set this way is managed by the caller.
+func WithLogger(logger *zap.Logger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithRegistry sets the service registry instead of creating one from configuration.
+func WithRegistry(registry discovery.Registry) Option {
+	return func(s *Service) {
+		s.registry = registry
+	}
+}
+
+// WithUnaryInterceptors adds unary server interceptors, they run after the tracing, logging, metrics,
+// authentication, authorization and rate limiting interceptors.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(s *Service) {
+		s.interceptors = append(s.interceptors, interceptors...)
+	}
+}
+
+// WithServerOptions adds gRPC server options.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(s *Service) {
+		s.serverOpts = append(s.serverOpts, opts...)
+	}
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Service defines a service bootstrap taking care of tracing, service registration,
+// health reporting, gRPC server lifecycle and graceful shutdown.
+type Service struct {
+	name         string
+	cfg          Config
+	logger       *zap.Logger
+	level        zap.AtomicLevel
+	limiter      *ratelimit.Limiter
+	auth         *auth.Authenticator
+	authz        *authz.Enforcer
+	creds        *tlsutil.Credentials
+	registry     discovery.Registry
+	health       *health.Monitor
+	instanceID   string
+	server       *grpc.Server
+	mux          *http.ServeMux
+	rest         *rest.Gateway
+	httpServer   *http.Server
+	admin        *admin.Server
+	adminServer  *http.Server
+	interceptors []grpc.UnaryServerInterceptor
+	serverOpts   []grpc.ServerOption
+	// invokeChain and streamChain hold interceptors REST requests run through,
+	// tracing and logging are done by the HTTP server.
+	invokeChain []grpc.UnaryServerInterceptor
+	streamChain []grpc.StreamServerInterceptor
+	// stopping is closed once shutdown starts, ending streams.
+	stopping chan struct{}
+
+	mu        sync.Mutex
+	hooks     []shutdownHook
+	instances []discovery.Instance
+}
+
+// New creates a new service, initializing tracing, the service registry and the gRPC server.
+func New(ctx context.Context, name string, cfg Config, opts ...Option) (*Service, error) {
+	s := &Service{name: name, cfg: cfg, instanceID: discovery.GenerateInstanceID(name), mux: http.NewServeMux(), stopping: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		logger, level, err := logging.New(cfg.Log, name)
+		if err != nil {
+			return nil, err
+		}
+		s.logger, s.level = logger, level
+	} else {
+		s.level = zap.NewAtomicLevel()
+	}
+	// Code without a request-scoped logger, e.g. background tasks, logs with the service logger.
+	zap.ReplaceGlobals(s.logger)
+
+	tp, err := tracing.NewProvider(ctx, cfg.Tracing, name, s.instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("initialize tracer provider: %w", err)
+	}
+	propagator, err := tracing.NewPropagator(cfg.Tracing.Propagators)
+	if err != nil {
+		return nil, fmt.Errorf("initialize trace propagator: %w", err)
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	s.OnShutdown("tracer provider", tp.Shutdown)
+
+	metricsRegistry, err := metrics.NewRegistry(name)
+	if err != nil {
+		return nil, fmt.Errorf("initialize metrics: %w", err)
+	}
+	s.Handle(metrics.Path, metrics.Handler(metricsRegistry))
+
+	if s.registry == nil {
+		registry, err := discoveryutil.NewRegistry(ctx, cfg.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("initialize service registry: %w", err)
+		}
+		s.registry = registry
+	}
+	s.registry = metrics.InstrumentRegistry(s.registry)
+
+	if s.auth, err = auth.New(cfg.Auth); err != nil {
+		return nil, fmt.Errorf("initialize authentication: %w", err)
+	}
+	s.authz = authz.New(cfg.Authz, s.logger)
+	s.limiter = ratelimit.New(cfg.RateLimit, nil)
+	s.invokeChain = append([]grpc.UnaryServerInterceptor{
+		metrics.UnaryServerInterceptor(),
+		s.auth.UnaryServerInterceptor(),
+		s.authz.UnaryServerInterceptor(),
+		s.limiter.UnaryServerInterceptor(),
+	}, s.interceptors...)
+	s.streamChain = []grpc.StreamServerInterceptor{
+		s.endStreams,
+		metrics.StreamServerInterceptor(),
+		s.auth.StreamServerInterceptor(),
+		s.authz.StreamServerInterceptor(),
+		s.limiter.StreamServerInterceptor(),
+	}
+	s.rest = rest.New(s.invoke, s.invokeStream)
+	s.Handle(openapi.SpecPath, openapi.Handler(name, s.rest.Routes))
+	docs := openapi.DocsHandler()
+	s.Handle(openapi.DocsPath, docs)
+	s.Handle(openapi.DocsPath+"/", docs)
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{
+			otelgrpc.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(s.logger),
+		}, s.invokeChain...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{
+			otelgrpc.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(s.logger),
+		}, s.streamChain...)...),
+	}
+	if s.creds, err = tlsutil.New(cfg.TLS, s.logger); err != nil {
+		return nil, fmt.Errorf("initialize transport security: %w", err)
+	}
+	if s.creds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(s.creds.ServerCredentials()))
+		s.Go("certificate reloader", s.creds.Run)
+	} else {
+		s.logger.Warn("Transport security is disabled, the gRPC server accepts plaintext connections")
+	}
+	s.server = grpc.NewServer(append(serverOpts, s.serverOpts...)...)
+	reflection.Register(s.server)
+	s.health = health.New(cfg.Lifecycle.CheckTimeout, name)
+	healthpb.RegisterHealthServer(s.server, s.health.Server())
+
+	s.admin = admin.New(s.level)
+	s.admin.Publish("/config", func() any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return config.Redact(s.cfg)
+	})
+	s.admin.Publish("/instance", func() any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.instances
+	})
+	s.admin.Publish("/health", func() any {
+		return s.health.Report()
+	})
+	return s, nil
+}
+
+// Name returns the service name.
+func (s *Service) Name() string {
+	return s.name
+}
+
+// InstanceID returns the service instance identifier used for registration.
+func (s *Service) InstanceID() string {
+	return s.instanceID
+}
+
+// Logger returns the service logger.
+func (s *Service) Logger() *zap.Logger {
+	return s.logger
+}
+
+// Registry returns the service registry.
+func (s *Service) Registry() discovery.Registry {
+	return s.registry
+}
+
+// Authenticator returns the request authenticator, e.g. for HTTP handlers.
+func (s *Service) Authenticator() *auth.Authenticator {
+	return s.auth
+}
+
+// Authorizer returns the authorization policy enforcer, e.g. for HTTP handlers.
+func (s *Service) Authorizer() *authz.Enforcer {
+	return s.authz
+}
+
+// ClientCredentials returns transport credentials for connections to another service,
+// or nil if transport security is disabled.
+func (s *Service) ClientCredentials(serviceName string) credentials.TransportCredentials {
+	if s.creds == nil {
+		return nil
+	}
+	return s.creds.ClientCredentials(serviceName)
+}
+
+// Admin returns the admin server for publishing runtime state, e.g. cache state, or the effective
+// configuration of the service, which only covers the shared configuration by default.
+func (s *Service) Admin() *admin.Server {
+	return s.admin
+}
+
+// Server returns the gRPC server for registering service handlers.
+func (s *Service) Server() *grpc.Server {
+	return s.server
+}
+
+// Handle registers an HTTP handler for the pattern. Requests are traced, authenticated and authorized
+// like gRPC requests, routes of authorization rules are matched as "<request method> <path>".
+// Handlers are only served if the HTTP server is enabled.
+func (s *Service) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleREST registers REST routes mapped onto gRPC methods of the service. Requests are traced and
+// logged by the HTTP server and then run through the gRPC interceptors of the mapped methods, so that
+// they are authenticated, authorized, rate limited and measured like gRPC requests.
+// Routes are only served if the HTTP server is enabled and are described by the OpenAPI document
+// served at /openapi.json and rendered at /docs.
+func (s *Service) HandleREST(routes ...rest.Route) {
+	s.rest.Handle(routes...)
+}
+
+// invoke calls a unary method handler through the interceptors of the gRPC server other than tracing and logging.
+func (s *Service) invoke(ctx context.Context, method string, req any, handler grpc.UnaryHandler) (any, error) {
+	info := &grpc.UnaryServerInfo{Server: s.server, FullMethod: method}
+	next := handler
+	for i := len(s.invokeChain) - 1; i >= 0; i-- {
+		interceptor, h := s.invokeChain[i], next
+		next = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, h)
+		}
+	}
+	return next(ctx, req)
+}
+
+// invokeStream calls a streaming method handler through the stream interceptors of the gRPC server
+// other than tracing and logging.
+func (s *Service) invokeStream(method string, stream grpc.ServerStream, handler grpc.StreamHandler) error {
+	info := &grpc.StreamServerInfo{FullMethod: method, IsServerStream: true}
+	next := handler
+	for i := len(s.streamChain) - 1; i >= 0; i-- {
+		interceptor, h := s.streamChain[i], next
+		next = func(srv any, stream grpc.ServerStream) error {
+			return interceptor(srv, stream, info, h)
+		}
+	}
+	return next(nil, stream)
+}
+
+// endStreams cancels stream contexts once shutdown starts, so that long-lived streams don't block it.
+// Streams ended this way fail with Unavailable, clients are expected to reopen them on other instances.
+func (s *Service) endStreams(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := handler(srv, &serverStream{ss, ctx})
+	select {
+	case <-s.stopping:
+		if ss.Context().Err() == nil {
+			return status.Error(codes.Unavailable, "service is shutting down")
+		}
+	default:
+	}
+	return err
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Reconfigure applies settings of a reloaded configuration which can change at runtime:
+// the log level, rate limits, authentication and authorization policy.
+// Changes of other settings are logged and take effect after a restart.
+func (s *Service) Reconfigure(cfg Config) error {
+	level, err := zapcore.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.cfg
+	if !reflect.DeepEqual(prev.Auth, cfg.Auth) {
+		if err := s.auth.Update(cfg.Auth); err != nil {
+			return err
+		}
+	}
+	s.cfg.Log.Level = cfg.Log.Level
+	s.cfg.RateLimit = cfg.RateLimit
+	s.cfg.Auth = cfg.Auth
+	s.cfg.Authz = cfg.Authz
+	// The level is only applied if it is changed, so that a level set on the admin server is kept.
+	if prev.Log.Level != cfg.Log.Level {
+		s.level.SetLevel(level)
+	}
+	s.authz.Update(cfg.Authz)
+	if !reflect.DeepEqual(prev.RateLimit, cfg.RateLimit) {
+		s.limiter.Update(cfg.RateLimit)
+	}
+
+	for _, c := range []struct {
+		name    string
+		changed bool
+	}{
+		{"api", prev.API != cfg.API},
+		{"http", prev.HTTP != cfg.HTTP},
+		{"admin", prev.Admin != cfg.Admin},
+		{"tracing", !reflect.DeepEqual(prev.Tracing, cfg.Tracing)},
+		{"registry", !reflect.DeepEqual(prev.Registry, cfg.Registry)},
+		{"lifecycle", prev.Lifecycle != cfg.Lifecycle},
+		{"tls", !reflect.DeepEqual(prev.TLS, cfg.TLS)},
+		{"log.format", prev.Log.Format != cfg.Log.Format},
+	} {
+		if c.changed {
+			s.logger.Warn("Configuration change requires a restart", zap.String("setting", c.name))
+		}
+	}
+	return nil
+}
+
+// OnShutdown registers a hook run on shutdown after the gRPC server is stopped.
+// Hooks run in the reverse order of registration.
+func (s *Service) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name, fn})
+}
+
+// Go runs fn in a new goroutine with a context which is cancelled on shutdown.
+// Shutdown waits for fn to return before running hooks registered earlier.
+func (s *Service) Go(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+	s.OnShutdown(name, func(shutdownCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-shutdownCtx.Done():
+			return shutdownCtx.Err()
+		}
+	})
+}
+
+// AddReadinessCheck registers a check of a dependency, e.g. a database, which must pass for the instance
+// to be ready. Readiness is published through the grpc.health.v1 service and the readiness probe.
+func (s *Service) AddReadinessCheck(name string, fn func(ctx context.Context) error) {
+	s.health.Add(name, fn)
+}
+
+// Ready runs readiness checks and reports whether the service is serving and all of them pass.
+func (s *Service) Ready(ctx context.Context) bool {
+	return s.health.Check(ctx).Ready
+}
+
+// Run registers the service instances, serves gRPC and HTTP requests and reports healthy state to the registry
+// while the service is ready. It blocks until ctx is done, SIGINT or SIGTERM is received or the server fails,
+// and then deregisters the instances, gracefully stops the servers and runs shutdown hooks.
+// The HTTP server is registered as a separate instance with the HTTP protocol, so that HTTP clients can find it.
+func (s *Service) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.cfg.API.Host, strconv.Itoa(s.cfg.API.Port)))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	var httpLis net.Listener
+	if s.cfg.HTTP.Enabled {
+		if httpLis, err = net.Listen("tcp", net.JoinHostPort(s.cfg.API.Host, strconv.Itoa(s.cfg.HTTP.Port))); err != nil {
+			lis.Close()
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	instances := []discovery.Instance{s.newInstance(s.instanceID, lis, discovery.ProtocolGRPC)}
+	if httpLis != nil {
+		instances = append(instances, s.newInstance(s.instanceID+"-http", httpLis, discovery.ProtocolHTTP))
+	}
+	for i, instance := range instances {
+		if err := s.registry.RegisterInstance(ctx, instance); err != nil {
+			lis.Close()
+			if httpLis != nil {
+				httpLis.Close()
+			}
+			s.deregister(ctx, instances[:i])
+			return fmt.Errorf("register service instance: %w", err)
+		}
+	}
+	s.mu.Lock()
+	s.instances = instances
+	s.mu.Unlock()
+
+	serveErr := make(chan error, 3)
+	go func() {
+		serveErr <- s.server.Serve(lis)
+	}()
+	if httpLis != nil {
+		s.serveHTTP(httpLis, serveErr)
+	}
+	if s.cfg.Admin.Enabled {
+		if err := s.serveAdmin(serveErr); err != nil {
+			s.logger.Error("Failed to start the admin server", zap.Error(err))
+			serveErr <- err
+		}
+	}
+	s.health.SetServing(true)
+	s.logger.Info("Started the "+s.name+" service", zap.Int("port", port), zap.String("instanceID", s.instanceID))
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.heartbeat(heartbeatCtx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.logger.Info("Received signal, attempting graceful shutdown")
+	case err = <-serveErr:
+		s.logger.Error("Server failed", zap.Error(err))
+	}
+
+	s.health.SetServing(false)
+	stopHeartbeat()
+	wg.Wait()
+	s.shutdown()
+	return err
+}
+
+// newInstance returns the registration of a server listening on lis.
+func (s *Service) newInstance(id string, lis net.Listener, protocol string) discovery.Instance {
+	host := s.cfg.API.AdvertiseHost
+	if host == "" {
+		host = s.cfg.API.Host
+	}
+	return discovery.Instance{
+		ID:          id,
+		ServiceName: s.name,
+		HostPort:    net.JoinHostPort(host, strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)),
+		Protocol:    protocol,
+	}
+}
+
+// deregister removes the instances from the registry, failures are logged.
+func (s *Service) deregister(ctx context.Context, instances []discovery.Instance) {
+	for _, instance := range instances {
+		if err := s.registry.Deregister(ctx, instance.ID, s.name); err != nil {
+			s.logger.Error("Failed to deregister service instance", zap.String("instanceID", instance.ID), zap.Error(err))
+		}
+	}
+}
+
+// serveHTTP starts serving registered HTTP handlers on lis, errors other than shutdown are sent to serveErr.
+func (s *Service) serveHTTP(lis net.Listener, serveErr chan<- error) {
+	// REST requests are authenticated and authorized by gRPC interceptors, other handlers by middleware.
+	handlers := s.auth.Middleware(s.authz.Middleware(s.mux))
+	routed := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if s.rest.Match(req) {
+			s.rest.ServeHTTP(w, req)
+			return
+		}
+		handlers.ServeHTTP(w, req)
+	})
+	api := otelhttp.NewHandler(logging.Middleware(s.logger)(routed), s.name,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return req.Method + " " + req.URL.Path
+		}),
+		otelhttp.WithFilter(func(req *http.Request) bool {
+			return req.URL.Path != metrics.Path
+		}),
+	)
+	// Probes bypass tracing, authentication and authorization.
+	handler := http.NewServeMux()
+	handler.Handle(health.LivePath, health.LiveHandler())
+	handler.Handle(health.ReadyPath, s.health.ReadyHandler())
+	handler.Handle("/", api)
+	s.httpServer = &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.httpServer.Serve(lis); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+	s.logger.Info("Started the HTTP server", zap.Int("port", lis.Addr().(*net.TCPAddr).Port))
+}
+
+// serveAdmin starts serving admin endpoints, errors other than shutdown are sent to serveErr.
+func (s *Service) serveAdmin(serveErr chan<- error) error {
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.cfg.Admin.Host, strconv.Itoa(s.cfg.Admin.Port)))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	s.adminServer = &http.Server{
+		Handler:           s.admin,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.adminServer.Serve(lis); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+	s.logger.Info("Started the admin server", zap.String("addr", lis.Addr().String()))
+	return nil
+}
+
+// heartbeat runs readiness checks and reports the healthy state to the registry while the instance is ready,
+// so that the registry stops routing requests to it once its TTL expires otherwise.
+func (s *Service) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Lifecycle.HeartbeatInterval)
+	defer ticker.Stop()
+	wasReady := true
+	for {
+		report := s.health.Check(ctx)
+		if report.Ready {
+			s.mu.Lock()
+			instances := s.instances
+			s.mu.Unlock()
+			for _, instance := range instances {
+				if err := s.registry.ReportHealthyState(instance.ID, s.name); err != nil {
+					s.logger.Error("Failed to report healthy state", zap.String("instanceID", instance.ID), zap.Error(err))
+				}
+			}
+		} else if wasReady && ctx.Err() == nil {
+			for _, c := range report.Checks {
+				if c.Error != "" {
+					s.logger.Warn("Readiness check failed", zap.String("check", c.Name), zap.String("error", c.Error))
+				}
+			}
+		}
+		if report.Ready && !wasReady {
+			s.logger.Info("Instance is ready again")
+		}
+		wasReady = report.Ready
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Lifecycle.ShutdownTimeout)
+	defer cancel()
+	s.health.Shutdown()
+	s.mu.Lock()
+	instances := s.instances
+	s.mu.Unlock()
+	s.deregister(ctx, instances)
+	close(s.stopping)
+	if s.httpServer != nil {
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Failed to stop the HTTP server", zap.Error(err))
+		}
+	}
+	// Requests still running when the shutdown timeout expires are cancelled.
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		s.logger.Info("Gracefully stopped the gRPC server")
+	case <-ctx.Done():
+		s.logger.Warn("Timed out draining gRPC requests, cancelling them")
+		s.server.Stop()
+		<-stopped
+	}
+	// The admin server is kept until requests are drained to allow debugging a stuck shutdown.
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Failed to stop the admin server", zap.Error(err))
+		}
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			s.logger.Error("Shutdown hook failed", zap.String("hook", hooks[i].name), zap.Error(err))
+		}
+	}
+	_ = s.logger.Sync()
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/internal/health"
+	"github.com/mkvy/movies-app/pkg/discovery"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := memory.NewRegistry()
+	svc, err := New(ctx, "lifecycle", DefaultConfig(), WithRegistry(registry), WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	var hooks []string
+	svc.OnShutdown("first", func(context.Context) error {
+		hooks = append(hooks, "first")
+		return nil
+	})
+	svc.OnShutdown("second", func(context.Context) error {
+		hooks = append(hooks, "second")
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		addrs, err := registry.ServiceAddresses(ctx, "lifecycle")
+		return err == nil && len(addrs) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, svc.Ready(ctx))
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("service is not stopped")
+	}
+	assert.False(t, svc.Ready(context.Background()))
+	_, err = registry.ServiceInstances(context.Background(), "lifecycle", discovery.Filter{})
+	assert.Equal(t, discovery.ErrNotFound, err, "instance is deregistered")
+	assert.Equal(t, []string{"second", "first"}, hooks[:2])
+}
+
+func TestRunRegistersHTTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := memory.NewRegistry()
+	cfg := DefaultConfig()
+	cfg.HTTP.Enabled = true
+	svc, err := New(ctx, "protocols", cfg, WithRegistry(registry), WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+	for _, protocol := range []string{discovery.ProtocolGRPC, discovery.ProtocolHTTP} {
+		assert.Eventually(t, func() bool {
+			instances, err := registry.ServiceInstances(ctx, "protocols", discovery.Filter{Protocol: protocol})
+			return err == nil && len(instances) == 1
+		}, time.Second, 10*time.Millisecond, protocol)
+	}
+	instances, err := registry.ServiceInstances(ctx, "protocols", discovery.Filter{Protocol: discovery.ProtocolHTTP})
+	assert.NoError(t, err)
+	resp, err := http.Get("http://" + instances[0].HostPort + health.LivePath)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "HTTP instance serves HTTP")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	_, err = registry.ServiceInstances(context.Background(), "protocols", discovery.Filter{})
+	assert.Equal(t, discovery.ErrNotFound, err, "instances are deregistered")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := memory.NewRegistry()
+	cfg := DefaultConfig()
+	cfg.Lifecycle.ShutdownTimeout = 100 * time.Millisecond
+	svc, err := New(ctx, "stuck", cfg, WithRegistry(registry), WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	started := make(chan struct{})
+	svc.Server().RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Stuck",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(_ any, ctx context.Context, _ func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}},
+	}, struct{}{})
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+	var instances []discovery.Instance
+	assert.Eventually(t, func() bool {
+		instances, err = registry.ServiceInstances(ctx, "stuck", discovery.Filter{})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	conn, err := grpc.Dial(instances[0].HostPort, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- conn.Invoke(context.Background(), "/test.Stuck/Wait", &emptypb.Empty{}, &emptypb.Empty{})
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("service is not stopped after the shutdown timeout")
+	}
+	assert.Error(t, <-callErr, "running requests are cancelled")
+}
+
+type reportingRegistry struct {
+	*memory.Registry
+	reports atomic.Int32
+}
+
+func (r *reportingRegistry) ReportHealthyState(instanceID string, serviceName string) error {
+	r.reports.Add(1)
+	return r.Registry.ReportHealthyState(instanceID, serviceName)
+}
+
+func TestHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := &reportingRegistry{Registry: memory.NewRegistry()}
+	cfg := DefaultConfig()
+	cfg.Lifecycle.HeartbeatInterval = 10 * time.Millisecond
+	svc, err := New(ctx, "heartbeat", cfg, WithRegistry(registry), WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	var dbUp atomic.Bool
+	svc.AddReadinessCheck("database", func(context.Context) error {
+		if !dbUp.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		return !svc.health.Report().Time.IsZero()
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, registry.reports.Load(), "healthy state isn't reported while not ready")
+	resp, err := svc.health.Server().Check(ctx, &healthpb.HealthCheckRequest{Service: "heartbeat"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	dbUp.Store(true)
+	assert.Eventually(t, func() bool {
+		return registry.reports.Load() > 0
+	}, time.Second, 10*time.Millisecond)
+	resp, err = svc.health.Server().Check(ctx, &healthpb.HealthCheckRequest{Service: "heartbeat"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// End records the error on the span, unless it wraps one of the expected errors, e.g. a not found error,
+// and ends the span. It is meant to be deferred with a pointer to the named error result.
+func End(span trace.Span, err *error, expected ...error) {
+	defer span.End()
+	if *err == nil {
+		return
+	}
+	for _, e := range expected {
+		if errors.Is(*err, e) {
+			return
+		}
+	}
+	span.RecordError(*err)
+	span.SetStatus(codes.Error, (*err).Error())
+}
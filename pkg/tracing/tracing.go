@@ -1,13 +1,228 @@
 package tracing
 
 import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"os"
 )
 
+// Supported exporters.
+const (
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+	ExporterFile     = "file"
+	// ExporterJaeger is deprecated upstream, Jaeger collectors accept OTLP since v1.35.
+	ExporterJaeger = "jaeger"
+	ExporterNone   = "none"
+)
+
+// Supported samplers, named as in the OTEL_TRACES_SAMPLER environment variable.
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// Supported propagators, named as in the OTEL_PROPAGATORS environment variable.
+const (
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+)
+
+// Config defines tracing configuration.
+type Config struct {
+	// Exporter is one of otlp-grpc, otlp-http, stdout, file, jaeger or none.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the collector host:port for OTLP exporters or the collector URL for the jaeger exporter.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure disables TLS of OTLP exporters.
+	Insecure bool `yaml:"insecure"`
+	// Path is the file the file exporter appends spans to, one JSON object per line.
+	Path string `yaml:"path"`
+	// Sampler is one of always_on, always_off, traceidratio and their parentbased_ variants
+	// following the sampling decision of the parent span.
+	Sampler string `yaml:"sampler"`
+	// Ratio is the fraction of traces sampled by ratio samplers.
+	Ratio float64 `yaml:"ratio"`
+	// Propagators lists trace context formats: tracecontext, baggage, b3 and b3multi.
+	// Context is injected in all formats and extracted from any of them.
+	Propagators []string `yaml:"propagators"`
+	// Version and Environment are set as service.version and deployment.environment resource attributes.
+	Version     string `yaml:"version"`
+	Environment string `yaml:"environment"`
+	// Attributes are additional resource attributes.
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// Validate checks the tracing configuration consistency.
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterJaeger:
+		if c.Endpoint == "" {
+			return fmt.Errorf("endpoint must be set for %s exporter", c.Exporter)
+		}
+	case ExporterFile:
+		if c.Path == "" {
+			return fmt.Errorf("path must be set for %s exporter", c.Exporter)
+		}
+	case ExporterStdout, ExporterNone:
+	default:
+		return fmt.Errorf("unsupported exporter %q", c.Exporter)
+	}
+	if _, err := newSampler(c.Sampler, c.Ratio); err != nil {
+		return err
+	}
+	_, err := NewPropagator(c.Propagators)
+	return err
+}
+
+// NewProvider creates a tracer provider exporting spans as configured. The service name and instance ID
+// are set as resource attributes.
+func NewProvider(ctx context.Context, cfg Config, serviceName string, instanceID string) (*tracesdk.TracerProvider, error) {
+	sampler, err := newSampler(cfg.Sampler, cfg.Ratio)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceInstanceIDKey.String(instanceID),
+	}
+	if cfg.Version != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(cfg.Version))
+	}
+	if cfg.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentKey.String(cfg.Environment))
+	}
+	for k, v := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	opts := []tracesdk.TracerProviderOption{
+		tracesdk.WithSampler(sampler),
+		tracesdk.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+	}
+	exp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+	if exp != nil {
+		opts = append(opts, tracesdk.WithBatcher(exp))
+	}
+	return tracesdk.NewTracerProvider(opts...), nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (tracesdk.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &fileExporter{exp, f}, nil
+	case ExporterJaeger:
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.Endpoint)))
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported exporter %q", cfg.Exporter)
+	}
+}
+
+// fileExporter closes the file spans are written to on shutdown.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	f *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func newSampler(name string, ratio float64) (tracesdk.Sampler, error) {
+	if (name == SamplerTraceIDRatio || name == SamplerParentBasedTraceIDRatio) && (ratio < 0 || ratio > 1) {
+		return nil, fmt.Errorf("ratio must be between 0 and 1, got %v", ratio)
+	}
+	switch name {
+	case SamplerAlwaysOn:
+		return tracesdk.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return tracesdk.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return tracesdk.TraceIDRatioBased(ratio), nil
+	case "", SamplerParentBasedAlwaysOn:
+		return tracesdk.ParentBased(tracesdk.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return tracesdk.ParentBased(tracesdk.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		return tracesdk.ParentBased(tracesdk.TraceIDRatioBased(ratio)), nil
+	}
+	return nil, fmt.Errorf("unsupported sampler %q", name)
+}
+
+// NewPropagator returns a propagator of the trace context formats, W3C trace context and baggage if empty.
+func NewPropagator(names []string) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		names = []string{PropagatorTraceContext, PropagatorBaggage}
+	}
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch name {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		default:
+			return nil, fmt.Errorf("unsupported propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
 // NewJaegerProvider returns a new jaeger-based tracing provider.
+//
+// Deprecated: the Jaeger exporter is deprecated upstream, use NewProvider with an OTLP exporter.
 func NewJaegerProvider(url string, serviceName string) (*tracesdk.TracerProvider, error) {
 	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
 	if err != nil {
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "otlp", cfg: Config{Exporter: ExporterOTLPGRPC, Endpoint: "localhost:4317"}},
+		{name: "missing endpoint", cfg: Config{Exporter: ExporterOTLPHTTP}, wantErr: true},
+		{name: "missing path", cfg: Config{Exporter: ExporterFile}, wantErr: true},
+		{name: "unsupported exporter", cfg: Config{Exporter: "zipkin"}, wantErr: true},
+		{name: "ratio", cfg: Config{Exporter: ExporterNone, Sampler: SamplerParentBasedTraceIDRatio, Ratio: 0.1}},
+		{name: "invalid ratio", cfg: Config{Exporter: ExporterNone, Sampler: SamplerTraceIDRatio, Ratio: 2}, wantErr: true},
+		{name: "unsupported sampler", cfg: Config{Exporter: ExporterNone, Sampler: "sometimes"}, wantErr: true},
+		{name: "b3", cfg: Config{Exporter: ExporterStdout, Propagators: []string{PropagatorTraceContext, PropagatorB3}}},
+		{name: "unsupported propagator", cfg: Config{Exporter: ExporterStdout, Propagators: []string{"xray"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	tp, err := NewProvider(ctx, Config{
+		Exporter:    ExporterFile,
+		Path:        path,
+		Sampler:     SamplerAlwaysOn,
+		Version:     "1.2.3",
+		Environment: "test",
+	}, "movie", "movie-1")
+	assert.NoError(t, err)
+	_, span := tp.Tracer("test").Start(ctx, "operation")
+	span.End()
+	assert.NoError(t, tp.Shutdown(ctx))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 1)
+	for _, s := range []string{`"Name":"operation"`, `"Value":"movie-1"`, `"Value":"1.2.3"`, `"Value":"test"`} {
+		assert.Contains(t, lines[0], s)
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	p, err := NewPropagator([]string{PropagatorTraceContext, PropagatorB3})
+	assert.NoError(t, err)
+	carrier := propagation.MapCarrier{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"}
+	ctx := p.Extract(context.Background(), carrier)
+
+	out := propagation.MapCarrier{}
+	p.Inject(ctx, out)
+	assert.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01", out.Get("traceparent"))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1", out.Get("b3"))
+}
+
+func TestEnd(t *testing.T) {
+	errNotFound := errors.New("not found")
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{name: "success", wantStatus: codes.Unset},
+		{name: "expected error", err: fmt.Errorf("get: %w", errNotFound), wantStatus: codes.Unset},
+		{name: "error", err: errors.New("connection refused"), wantStatus: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+			func() (err error) {
+				_, span := tp.Tracer("test").Start(context.Background(), "operation")
+				defer End(span, &err, errNotFound)
+				return tt.err
+			}()
+			spans := recorder.Ended()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, tt.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
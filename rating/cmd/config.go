@@ -1,14 +1,56 @@
 package main
 
+import (
+	"github.com/mkvy/movies-app/internal/auditutil"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
+	"github.com/mkvy/movies-app/rating/internal/controller/rating"
+)
+
 type config struct {
-	API apiConfig `yaml:"api"`
-	Jaeger jaegerConfig `yaml:"jaeger"`
+	service.Config `yaml:",inline"`
+	Database       databaseConfig   `yaml:"database"`
+	Audit          auditutil.Config `yaml:"audit"`
+	Ingester       ingesterConfig   `yaml:"ingester"`
+	// Aggregation defines how ratings are aggregated, it can be changed at runtime.
+	Aggregation rating.Aggregation `yaml:"aggregation"`
+}
+
+type databaseConfig struct {
+	DSN string `yaml:"dsn" secret:"true"`
 }
 
-type apiConfig struct {
-	Port int `yaml:"port"`
+type ingesterConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+	GroupID string `yaml:"groupId"`
+	Topic   string `yaml:"topic"`
 }
 
-type jaegerConfig struct {
-	URL string `yaml:"url"`
-}
\ No newline at end of file
+func defaultConfig() config {
+	return config{
+		Config:      service.DefaultConfig(),
+		Database:    databaseConfig{DSN: "root:password@/movieexample"},
+		Ingester:    ingesterConfig{Addr: "localhost:9092", GroupID: "rating", Topic: "ratings"},
+		Aggregation: rating.AggregationMean,
+	}
+}
+
+// Validate checks the configuration consistency.
+func (c config) Validate() error {
+	var errs appconfig.Errors
+	errs.Add(c.Config.Validate())
+	if c.Database.DSN == "" {
+		errs.Addf("database.dsn must be set")
+	}
+	if err := c.Audit.Validate(); err != nil {
+		errs.Addf("audit: %v", err)
+	}
+	if c.Ingester.Enabled && (c.Ingester.Addr == "" || c.Ingester.GroupID == "" || c.Ingester.Topic == "") {
+		errs.Addf("ingester.addr, ingester.groupId and ingester.topic must be set when ingester is enabled")
+	}
+	if err := c.Aggregation.Validate(); err != nil {
+		errs.Addf("aggregation: %v", err)
+	}
+	return errs.Err()
+}
@@ -2,108 +2,87 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"github.com/mkvy/movies-app/gen"
-	"github.com/mkvy/movies-app/pkg/discovery"
-	"github.com/mkvy/movies-app/pkg/discovery/consul"
-	"github.com/mkvy/movies-app/pkg/tracing"
+	"github.com/mkvy/movies-app/internal/auditutil"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/service"
 	"github.com/mkvy/movies-app/rating/internal/controller/rating"
 	grpchandler "github.com/mkvy/movies-app/rating/internal/handler/grpc"
+	httphandler "github.com/mkvy/movies-app/rating/internal/handler/http"
+	"github.com/mkvy/movies-app/rating/internal/ingester/kafka"
 	"github.com/mkvy/movies-app/rating/internal/repository/mysql"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
-	"net"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
 )
 
 const serviceName = "rating"
-const registryConsulAddr = "localhost:8500"
 
 func main() {
 	logger, _ := zap.NewProduction()
-	// if not docker image:
-	f, err := os.Open("./rating/configs/base.yaml")
-	//if docker:
-	//f, err := os.Open("base.yaml")
-	if err != nil {
-		logger.Fatal("Failed to open configuration", zap.Error(err))
+	defer logger.Sync()
+	cfg := defaultConfig()
+	loader := appconfig.NewLoader(serviceName, "./rating/configs")
+	if err := loader.Load(&cfg, os.Args[1:]); err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
-	var cfg config
-	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
-		logger.Fatal("Failed to parse configuration", zap.Error(err))
+	if loader.PrintRequested() {
+		if err := appconfig.Print(os.Stdout, cfg); err != nil {
+			logger.Fatal("Failed to print configuration", zap.Error(err))
+		}
+		return
 	}
-	port := cfg.API.Port
-	logger.Info("Starting the rating service", zap.Int("port", port))
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := tracing.NewJaegerProvider(cfg.Jaeger.URL, serviceName)
+	ctx := context.Background()
+	svc, err := service.New(ctx, serviceName, cfg.Config)
 	if err != nil {
-		logger.Fatal("Failed to initialize Jaeger provider", zap.Error(err))
+		logger.Fatal("Failed to initialize the rating service", zap.Error(err))
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			logger.Fatal("Failed to shutdown Jaeger provider", zap.Error(err))
-		}
-	}()
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	registry, err := consul.NewRegistry(registryConsulAddr)
+	logger = svc.Logger()
+	repo, err := mysql.New(cfg.Database.DSN)
 	if err != nil {
-		panic(err)
+		logger.Fatal("Error while initializing repository", zap.Error(err))
 	}
-
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%d", port)); err != nil {
-		panic(err)
+	svc.AddReadinessCheck("database", repo.Ping)
+	auditor, err := auditutil.NewLogger(cfg.Audit, cfg.Database.DSN)
+	if err != nil {
+		logger.Fatal("Failed to initialize audit log", zap.Error(err))
 	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				logger.Error("Failed to report healthy state", zap.Error(err))
-			}
-			time.Sleep(1 * time.Second)
+	if cfg.Audit.Enabled() {
+		svc.Handle("/v1/audit", auditor.Handler())
+	}
+	ctrl := rating.New(repo, nil, auditor)
+	if cfg.Ingester.Enabled {
+		ingester, err := kafka.NewIngester(cfg.Ingester.Addr, cfg.Ingester.GroupID, cfg.Ingester.Topic)
+		if err != nil {
+			logger.Fatal("Failed to initialize ingester", zap.Error(err))
 		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-	repo, err := mysql.New()
-	if err != nil {
-		logger.Fatal("Error while initializing repository", zap.Error(err))
+		ctrl = rating.New(repo, ingester, auditor)
+		svc.AddReadinessCheck("kafka", ingester.Check)
+		svc.Go("ingestion", func(ctx context.Context) {
+			if err := ctrl.StartIngestion(ctx); err != nil {
+				logger.Error("Rating ingestion failed", zap.Error(err))
+			}
+		})
 	}
-	ctrl := rating.New(repo, nil)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
-	if err != nil {
-		logger.Fatal("Failed to listen", zap.Error(err))
+	if err := ctrl.SetAggregation(cfg.Aggregation); err != nil {
+		logger.Fatal("Failed to set rating aggregation", zap.Error(err))
 	}
-	srv := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()))
-	reflection.Register(srv)
-	gen.RegisterRatingServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		_ = <-sigChan
-		cancel()
-		logger.Info("Recieved signal, attempting graceful shutdown")
-		srv.GracefulStop()
-		logger.Info("Gracefully stopped the gRPC server")
-	}()
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	watcher := appconfig.NewWatcher(loader, cfg, defaultConfig, logger)
+	svc.Admin().Publish("/config", func() any {
+		return appconfig.Redact(watcher.Current())
+	})
+	watcher.Subscribe("service", func(cfg config) error {
+		return svc.Reconfigure(cfg.Config)
+	})
+	watcher.Subscribe("aggregation", func(cfg config) error {
+		return ctrl.SetAggregation(cfg.Aggregation)
+	})
+	svc.Go("config watcher", func(ctx context.Context) {
+		watcher.Run(ctx, cfg.Lifecycle.ReloadInterval)
+	})
+	h := grpchandler.New(ctrl)
+	gen.RegisterRatingServiceServer(svc.Server(), h)
+	svc.HandleREST(httphandler.Routes(h)...)
+	if err := svc.Run(ctx); err != nil {
+		logger.Fatal("Failed to run the rating service", zap.Error(err))
 	}
-	wg.Wait()
 }
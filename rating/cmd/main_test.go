@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"github.com/mkvy/movies-app/gen"
+	appconfig "github.com/mkvy/movies-app/pkg/config"
+	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/mkvy/movies-app/pkg/service"
+	"github.com/mkvy/movies-app/rating/internal/controller/rating"
+	grpchandler "github.com/mkvy/movies-app/rating/internal/handler/grpc"
+	repository "github.com/mkvy/movies-app/rating/internal/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestBaseConfig writes ratings without credentials, which the base configuration allows.
+func TestBaseConfig(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	port := lis.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, lis.Close())
+	cfg := defaultConfig()
+	loader := appconfig.NewLoader(serviceName, "../configs")
+	assert.NoError(t, loader.Load(&cfg, []string{"-env=", "-api.port=" + strconv.Itoa(port), "-http.enabled=false", "-tracing.exporter=none"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc, err := service.New(ctx, serviceName, cfg.Config, service.WithRegistry(memory.NewRegistry()), service.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	gen.RegisterRatingServiceServer(svc.Server(), grpchandler.New(rating.New(repository.New(), nil, nil)))
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+
+	conn, err := grpc.Dial(net.JoinHostPort(cfg.API.Host, strconv.Itoa(port)), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := gen.NewRatingServiceClient(conn)
+	assert.Eventually(t, func() bool {
+		_, err := client.PutRating(ctx, &gen.PutRatingRequest{RecordId: "1", RecordType: "movie", UserId: "user0", RatingValue: 4})
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	_, err = client.PutRating(ctx, &gen.PutRatingRequest{RecordId: "1", RecordType: "movie", RatingValue: 2})
+	assert.NoError(t, err, "anonymous rating")
+	resp, err := client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: "1", RecordType: "movie"})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), resp.RatingValue)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -3,59 +3,213 @@ package rating
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/mkvy/movies-app/internal/pubsub"
+	"github.com/mkvy/movies-app/pkg/logging"
+	"github.com/mkvy/movies-app/pkg/tracing"
 	"github.com/mkvy/movies-app/rating/internal/repository"
 	"github.com/mkvy/movies-app/rating/pkg/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"sort"
+	"sync/atomic"
+	"time"
 )
 
+var tracer = otel.Tracer("github.com/mkvy/movies-app/rating/internal/controller/rating")
+
 var ErrNotFound = errors.New("ratings not found for record")
 
+// Aggregation defines how ratings of a record are aggregated into a single value.
+type Aggregation string
+
+const (
+	AggregationMean   Aggregation = "mean"
+	AggregationMedian Aggregation = "median"
+)
+
+// Validate checks that the aggregation is supported.
+func (a Aggregation) Validate() error {
+	switch a {
+	case AggregationMean, AggregationMedian:
+		return nil
+	}
+	return fmt.Errorf("unsupported aggregation %q", a)
+}
+
 type ratingRepository interface {
 	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
 	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
 }
 
 type ratingIngester interface {
-	Ingest(ctx context.Context) (chan model.RatingEvent, error)
+	Ingest(ctx context.Context, handle func(ctx context.Context, event model.RatingEvent) error) error
+}
+
+type auditor interface {
+	Record(ctx context.Context, action string, target string, before any, after any) error
 }
 
+// ActionPut is the audited action of putting a rating.
+const ActionPut = "rating.put"
+
+// watchPollInterval bounds the delay of watchers noticing ratings written by other instances.
+const watchPollInterval = 5 * time.Second
+
 type Controller struct {
-	repo     ratingRepository
-	ingester ratingIngester
+	repo        ratingRepository
+	ingester    ratingIngester
+	auditor     auditor
+	aggregation atomic.Value
+	changes     *pubsub.Broker
 }
 
-func New(repo ratingRepository, ingester ratingIngester) *Controller {
-	return &Controller{repo, ingester}
+// New creates a new controller. Ingester and auditor are optional, ratings aren't audited if auditor is nil.
+func New(repo ratingRepository, ingester ratingIngester, auditor auditor) *Controller {
+	c := &Controller{repo: repo, ingester: ingester, auditor: auditor, changes: pubsub.New()}
+	c.aggregation.Store(AggregationMean)
+	return c
 }
 
-func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
+// SetAggregation changes the aggregation used by GetAggregatedRating, it is safe to call while serving requests.
+func (c *Controller) SetAggregation(a Aggregation) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	c.aggregation.Store(a)
+	return nil
+}
+
+func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "rating.Controller.GetAggregatedRating", trace.WithAttributes(recordAttributes(recordID, recordType)...))
+	defer tracing.End(span, &err, ErrNotFound)
 	ratings, err := c.repo.Get(ctx, recordID, recordType)
 	if err != nil && err == repository.ErrNotFound {
 		return 0, ErrNotFound
 	} else if err != nil {
 		return 0, err
 	}
+	aggregation := c.aggregation.Load().(Aggregation)
+	span.SetAttributes(attribute.Int("rating.count", len(ratings)), attribute.String("rating.aggregation", string(aggregation)))
+	return aggregate(ratings, aggregation), nil
+}
+
+// GetAggregatedRatings returns aggregated ratings of records of the type, records without ratings are omitted.
+func (c *Controller) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (_ map[model.RecordID]float64, err error) {
+	ctx, span := tracer.Start(ctx, "rating.Controller.GetAggregatedRatings", trace.WithAttributes(
+		attribute.Int("rating.record_count", len(recordIDs)),
+		attribute.String("rating.record_type", string(recordType)),
+	))
+	defer tracing.End(span, &err)
+	res := map[model.RecordID]float64{}
+	for _, id := range recordIDs {
+		v, err := c.GetAggregatedRating(ctx, id, recordType)
+		if err != nil && err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res[id] = v
+	}
+	return res, nil
+}
+
+// Watch calls send with the aggregated rating of the record once it is rated and then again after each change,
+// until ctx is done or send fails. Ratings written by other instances are noticed by polling the repository.
+func (c *Controller) Watch(ctx context.Context, recordID model.RecordID, recordType model.RecordType, send func(float64) error) error {
+	var last *float64
+	return c.changes.Watch(ctx, changeKey(recordID, recordType), watchPollInterval, func(ctx context.Context) error {
+		ratings, err := c.repo.Get(ctx, recordID, recordType)
+		if err != nil && err == repository.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		v := aggregate(ratings, c.aggregation.Load().(Aggregation))
+		if last != nil && *last == v {
+			return nil
+		}
+		last = &v
+		return send(v)
+	})
+}
+
+func changeKey(recordID model.RecordID, recordType model.RecordType) string {
+	return string(recordType) + "/" + string(recordID)
+}
+
+func recordAttributes(recordID model.RecordID, recordType model.RecordType) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("rating.record_id", string(recordID)),
+		attribute.String("rating.record_type", string(recordType)),
+	}
+}
+
+func aggregate(ratings []model.Rating, aggregation Aggregation) float64 {
+	if aggregation == AggregationMedian {
+		return median(ratings)
+	}
 	sum := float64(0)
 	for _, r := range ratings {
 		sum += float64(r.Value)
 	}
-	return sum / float64(len(ratings)), nil
+	return sum / float64(len(ratings))
 }
 
-func (c *Controller) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	return c.repo.Put(ctx, recordID, recordType, rating)
+func median(ratings []model.Rating) float64 {
+	values := make([]float64, len(ratings))
+	for i, r := range ratings {
+		values[i] = float64(r.Value)
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
 }
 
-// StartIngestion starts the ingestion of rating events.
-// todo remake
-func (s *Controller) StartIngestion(ctx context.Context) error {
-	ch, err := s.ingester.Ingest(ctx)
-	if err != nil {
+// PutRating writes a rating and records it in the audit log along with the previous rating of the user.
+// Failures to record the rating are logged, as the rating is already written.
+func (c *Controller) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (err error) {
+	ctx, span := tracer.Start(ctx, "rating.Controller.PutRating", trace.WithAttributes(recordAttributes(recordID, recordType)...))
+	span.SetAttributes(attribute.String("rating.user_id", string(rating.UserID)))
+	defer tracing.End(span, &err)
+	if c.auditor == nil {
+		if err := c.repo.Put(ctx, recordID, recordType, rating); err != nil {
+			return err
+		}
+		c.changes.Publish(changeKey(recordID, recordType))
+		return nil
+	}
+	ratings, err := c.repo.Get(ctx, recordID, recordType)
+	if err != nil && err != repository.ErrNotFound {
 		return err
 	}
-	for e := range ch {
-		if err := s.PutRating(ctx, e.RecordID, e.RecordType, &model.Rating{UserID: e.UserID, Value: e.Value}); err != nil {
-			return err
+	if err := c.repo.Put(ctx, recordID, recordType, rating); err != nil {
+		return err
+	}
+	c.changes.Publish(changeKey(recordID, recordType))
+	var before *model.Rating
+	for i := range ratings {
+		if ratings[i].UserID == rating.UserID {
+			before = &ratings[i]
 		}
 	}
+	target := fmt.Sprintf("rating/%s/%s/%s", recordType, recordID, rating.UserID)
+	if err := c.auditor.Record(ctx, ActionPut, target, before, rating); err != nil {
+		span.RecordError(err)
+		logging.FromContext(ctx).Error("Failed to record audit entry", zap.String("action", ActionPut), zap.String("target", target), zap.Error(err))
+	}
 	return nil
 }
+
+// StartIngestion ingests rating events until ctx is done or a rating can't be written.
+// Ratings are written with the context passed by the ingester, so that they are traced as part of the event.
+func (c *Controller) StartIngestion(ctx context.Context) error {
+	return c.ingester.Ingest(ctx, func(ctx context.Context, e model.RatingEvent) error {
+		return c.PutRating(ctx, e.RecordID, e.RecordType, &model.Rating{UserID: e.UserID, Value: e.Value})
+	})
+}
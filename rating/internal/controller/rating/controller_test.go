@@ -0,0 +1,147 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"github.com/mkvy/movies-app/rating/internal/repository"
+	"github.com/mkvy/movies-app/rating/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type repositoryStub struct {
+	mu      sync.Mutex
+	ratings []model.Rating
+	err     error
+}
+
+func (r *repositoryStub) Get(context.Context, model.RecordID, model.RecordType) ([]model.Rating, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.ratings) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return append([]model.Rating(nil), r.ratings...), nil
+}
+
+func (r *repositoryStub) Put(_ context.Context, _ model.RecordID, _ model.RecordType, rating *model.Rating) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ratings = append(r.ratings, *rating)
+	return nil
+}
+
+type auditRecord struct {
+	action string
+	target string
+	before any
+	after  any
+}
+
+type auditorStub struct {
+	records []auditRecord
+	err     error
+}
+
+func (a *auditorStub) Record(_ context.Context, action string, target string, before any, after any) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.records = append(a.records, auditRecord{action, target, before, after})
+	return nil
+}
+
+func ratings(values ...model.RatingValue) []model.Rating {
+	var res []model.Rating
+	for _, v := range values {
+		res = append(res, model.Rating{UserID: "user", Value: v})
+	}
+	return res
+}
+
+func TestGetAggregatedRating(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratings     []model.Rating
+		repoErr     error
+		aggregation Aggregation
+		want        float64
+		wantErr     error
+	}{
+		{name: "mean", ratings: ratings(1, 2, 6), aggregation: AggregationMean, want: 3},
+		{name: "median", ratings: ratings(6, 1, 2), aggregation: AggregationMedian, want: 2},
+		{name: "median of even count", ratings: ratings(4, 1, 3, 2), aggregation: AggregationMedian, want: 2.5},
+		{name: "not found", aggregation: AggregationMean, wantErr: ErrNotFound},
+		{name: "unexpected error", repoErr: errors.New("unexpected error"), aggregation: AggregationMean, wantErr: errors.New("unexpected error")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&repositoryStub{ratings: tt.ratings, err: tt.repoErr}, nil, nil)
+			assert.NoError(t, c.SetAggregation(tt.aggregation))
+			got, err := c.GetAggregatedRating(context.Background(), "1", model.RecordTypeMovie)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+	assert.Error(t, New(&repositoryStub{}, nil, nil).SetAggregation("mode"))
+}
+
+func TestGetAggregatedRatings(t *testing.T) {
+	repo := &repositoryStub{ratings: ratings(2, 4)}
+	res, err := New(repo, nil, nil).GetAggregatedRatings(context.Background(), []model.RecordID{"1"}, model.RecordTypeMovie)
+	assert.NoError(t, err)
+	assert.Equal(t, map[model.RecordID]float64{"1": 3}, res)
+
+	res, err = New(&repositoryStub{}, nil, nil).GetAggregatedRatings(context.Background(), []model.RecordID{"1"}, model.RecordTypeMovie)
+	assert.NoError(t, err)
+	assert.Empty(t, res, "records without ratings are omitted")
+}
+
+func TestPutRating(t *testing.T) {
+	ctx := context.Background()
+	auditor := &auditorStub{}
+	c := New(&repositoryStub{}, nil, auditor)
+	first := &model.Rating{UserID: "alice", Value: 3}
+	second := &model.Rating{UserID: "alice", Value: 5}
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, first))
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, second))
+	assert.Equal(t, []auditRecord{
+		{action: ActionPut, target: "rating/movie/1/alice", before: (*model.Rating)(nil), after: first},
+		{action: ActionPut, target: "rating/movie/1/alice", before: first, after: second},
+	}, auditor.records)
+
+	// The rating is written already when recording it fails.
+	auditor.err = errors.New("audit log unavailable")
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, &model.Rating{UserID: "bob", Value: 1}))
+	v, err := c.GetAggregatedRating(ctx, "1", model.RecordTypeMovie)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), v)
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := New(&repositoryStub{}, nil, nil)
+	updates := make(chan float64)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Watch(ctx, "1", model.RecordTypeMovie, func(v float64) error {
+			updates <- v
+			return nil
+		})
+	}()
+
+	// Nothing is sent until the record is rated.
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, &model.Rating{UserID: "alice", Value: 4}))
+	assert.Equal(t, float64(4), <-updates)
+	// Unchanged aggregated ratings aren't sent again.
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, &model.Rating{UserID: "bob", Value: 4}))
+	assert.NoError(t, c.PutRating(ctx, "1", model.RecordTypeMovie, &model.Rating{UserID: "carol", Value: 1}))
+	assert.Equal(t, float64(3), <-updates)
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
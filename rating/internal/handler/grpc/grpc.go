@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/auth"
+	"github.com/mkvy/movies-app/internal/authz"
 	"github.com/mkvy/movies-app/rating/internal/controller/rating"
 	"github.com/mkvy/movies-app/rating/pkg/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// maxBatchSize limits the number of records of batch requests.
+const maxBatchSize = 100
+
 // Handler defines a gRPC rating API handler.
 type Handler struct {
 	gen.UnimplementedRatingServiceServer
@@ -35,12 +40,62 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *gen.GetAggregate
 	return &gen.GetAggregatedRatingResponse{RatingValue: v}, nil
 }
 
-// PutRating writes a rating for a given record.
+// BatchGetAggregatedRatings returns aggregated ratings of multiple records, records without ratings are omitted.
+func (h *Handler) BatchGetAggregatedRatings(ctx context.Context, req *gen.BatchGetAggregatedRatingsRequest) (*gen.BatchGetAggregatedRatingsResponse, error) {
+	if req == nil || req.RecordType == "" || len(req.RecordIds) == 0 || len(req.RecordIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req, empty type or not between 1 and %d ids", maxBatchSize)
+	}
+	ids := make([]model.RecordID, 0, len(req.RecordIds))
+	for _, id := range req.RecordIds {
+		ids = append(ids, model.RecordID(id))
+	}
+	res, err := h.ctrl.GetAggregatedRatings(ctx, ids, model.RecordType(req.RecordType))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	values := make(map[string]float64, len(res))
+	for id, v := range res {
+		values[string(id)] = v
+	}
+	return &gen.BatchGetAggregatedRatingsResponse{RatingValues: values}, nil
+}
+
+// WatchAggregatedRating streams the aggregated rating of a record once it is rated and its changes
+// until the client cancels the call.
+func (h *Handler) WatchAggregatedRating(req *gen.WatchAggregatedRatingRequest, stream gen.RatingService_WatchAggregatedRatingServer) error {
+	if req == nil || req.RecordId == "" || req.RecordType == "" {
+		return status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	ctx := stream.Context()
+	err := h.ctrl.Watch(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), func(v float64) error {
+		return stream.Send(&gen.WatchAggregatedRatingResponse{RatingValue: v})
+	})
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	} else if _, ok := status.FromError(err); !ok {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	return err
+}
+
+// PutRating writes a rating of the authenticated user for a given record.
+// Admins, and any caller while authentication is disabled, may write ratings on behalf of other users.
 func (h *Handler) PutRating(ctx context.Context, req *gen.PutRatingRequest) (*gen.PutRatingResponse, error) {
-	if req == nil || req.RecordId == "" || req.UserId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
+	if req == nil || req.RecordId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record id")
+	}
+	p, ok := auth.Caller(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	userID := p.Subject
+	if req.UserId != "" && req.UserId != p.Subject {
+		if p.Method != auth.MethodNone && !p.HasRole(authz.RoleAdmin) {
+			return nil, status.Errorf(codes.PermissionDenied, "user id doesn't match the authenticated user")
+		}
+		userID = req.UserId
 	}
-	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.RatingValue)}); err != nil {
+	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(userID), Value: model.RatingValue(req.RatingValue)}); err != nil {
 		return nil, err
 	}
 	return &gen.PutRatingResponse{}, nil
@@ -1,59 +1,28 @@
 package http
 
 import (
-	"encoding/json"
-	"errors"
-	"github.com/mkvy/movies-app/rating/internal/controller/rating"
-	"github.com/mkvy/movies-app/rating/pkg/model"
-	"log"
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/rest"
 	"net/http"
-	"strconv"
 )
 
-// Handler defines a HTTP rating handler.
-type Handler struct {
-	ctrl *rating.Controller
-}
-
-// New creates a new rating service HTTP handler.
-func New(ctrl *rating.Controller) *Handler {
-	return &Handler{ctrl}
-}
-
-// Handle handles PUT and GET /rating requests.
-func (h *Handler) Handle(w http.ResponseWriter, req *http.Request) {
-	recordID := model.RecordID(req.FormValue("id"))
-	if recordID == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	recordType := model.RecordType(req.FormValue("type"))
-	if recordType == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	switch req.Method {
-	case http.MethodGet:
-		v, err := h.ctrl.GetAggregatedRating(req.Context(), recordID, recordType)
-		if err != nil && errors.Is(err, rating.ErrNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		if err := json.NewEncoder(w).Encode(v); err != nil {
-			log.Printf("Response encode error: %v\n", err)
-		}
-	case http.MethodPut:
-		userID := model.UserID(req.FormValue("userId"))
-		v, err := strconv.ParseFloat(req.FormValue("value"), 64)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		if err := h.ctrl.PutRating(req.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)}); err != nil {
-			log.Printf("Repository put error: %v\n", err)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-	default:
-		w.WriteHeader(http.StatusBadRequest)
+// Routes returns REST routes of the rating service mapped onto its gRPC methods.
+// Ratings are written by the authenticated user, admins may pass another userId.
+func Routes(h gen.RatingServiceServer) []rest.Route {
+	return []rest.Route{
+		{
+			Method:  http.MethodGet,
+			Pattern: "/v1/ratings/{record_type}/{record_id}",
+			RPC:     gen.RatingService_GetAggregatedRating_FullMethodName,
+			Call:    rest.Unary(h.GetAggregatedRating),
+		},
+		{
+			Method:  http.MethodPost,
+			Pattern: "/v1/ratings",
+			RPC:     gen.RatingService_PutRating_FullMethodName,
+			Call:    rest.Unary(h.PutRating),
+			Body:    "*",
+			Status:  http.StatusNoContent,
+		},
 	}
 }
@@ -0,0 +1,13 @@
+package http
+
+import (
+	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/openapi/openapitest"
+	"testing"
+)
+
+// TestOpenAPI fails if the checked in OpenAPI document differs from the one generated from the routes,
+// regenerate it with go test ./rating/internal/handler/http -update.
+func TestOpenAPI(t *testing.T) {
+	openapitest.AssertDocument(t, "rating", Routes(gen.UnimplementedRatingServiceServer{}), "../../../../api/openapi/rating.json")
+}
@@ -3,15 +3,31 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/mkvy/movies-app/internal/kafkatrace"
+	"github.com/mkvy/movies-app/internal/metrics"
+	"github.com/mkvy/movies-app/pkg/logging"
 	"github.com/mkvy/movies-app/rating/pkg/model"
-	"log"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// pollTimeout bounds the time waiting for a message, so that ingestion stops soon after ctx is done.
+const pollTimeout = time.Second
+
 // Ingester defines a Kafka ingester.
 type Ingester struct {
-	consumer kafka.Consumer
-	topic    string
+	groupID string
+	topic   string
+
+	// mu guards the consumer against use by Check once it is closed.
+	mu       sync.RWMutex
+	consumer *kafka.Consumer
+	closed   bool
 }
 
 // NewIngester creates a new Kafka ingester.
@@ -24,38 +40,98 @@ func NewIngester(addr string, groupID string, topic string) (*Ingester, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Ingester{*consumer, topic}, nil
+	return &Ingester{groupID: groupID, topic: topic, consumer: consumer}, nil
 }
 
-// Ingest starts ingestion from Kafka and returns a channel containing rating events
-// representing the data consumed from the topic.
-func (i *Ingester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
+// Ingest consumes rating events from the topic and passes them to handle along with a context
+// carrying a consumer span, which continues the trace of the producer.
+// It blocks until ctx is done or handle fails and closes the consumer.
+func (i *Ingester) Ingest(ctx context.Context, handle func(ctx context.Context, event model.RatingEvent) error) error {
+	defer i.close()
 	if err := i.consumer.SubscribeTopics([]string{i.topic}, nil); err != nil {
-		return nil, err
+		return err
 	}
-
-	ch := make(chan model.RatingEvent, 1)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(ch)
-				i.consumer.Close()
-				return
-			default:
+	for ctx.Err() == nil {
+		msg, err := i.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); !ok || kafkaErr.Code() != kafka.ErrTimedOut {
+				logging.FromContext(ctx).Error("Failed to read rating event", zap.Error(err))
 			}
-			msg, err := i.consumer.ReadMessage(-1)
-			if err != nil {
-				log.Println("Consumer error: " + err.Error())
-				continue
-			}
-			var event model.RatingEvent
-			if err := json.Unmarshal(msg.Value, &event); err != nil {
-				log.Println("Unmarshal error: " + err.Error())
-				continue
-			}
-			ch <- event
+			continue
+		}
+		i.observeLag(msg.TopicPartition)
+		if err := i.process(ctx, msg, handle); err != nil {
+			return err
 		}
-	}()
-	return ch, nil
+	}
+	return nil
+}
+
+func (i *Ingester) process(ctx context.Context, msg *kafka.Message, handle func(ctx context.Context, event model.RatingEvent) error) error {
+	ctx, span := kafkatrace.StartConsumerSpan(ctx, msg, i.groupID)
+	defer span.End()
+	var event model.RatingEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode rating event", zap.Error(err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid rating event")
+		metrics.IncConsumed(i.topic, metrics.ResultError)
+		return nil
+	}
+	if err := handle(ctx, event); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		metrics.IncConsumed(i.topic, metrics.ResultError)
+		return err
+	}
+	metrics.IncConsumed(i.topic, metrics.ResultOK)
+	return nil
+}
+
+func (i *Ingester) close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.closed = true
+	i.consumer.Close()
+}
+
+// Check checks that the brokers are reachable and the topic exists, e.g. for readiness checks.
+// It fails once ingestion has stopped.
+func (i *Ingester) Check(ctx context.Context) error {
+	timeout := pollTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.closed {
+		return errors.New("ingestion is stopped")
+	}
+	md, err := i.consumer.GetMetadata(&i.topic, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	if t, ok := md.Topics[i.topic]; !ok {
+		return errors.New("topic " + i.topic + " not found")
+	} else if t.Error.Code() != kafka.ErrNoError {
+		return t.Error
+	}
+	return nil
+}
+
+// observeLag records the number of messages of the partition after the consumed one.
+// The high watermark is the one cached from the last fetch, so no broker request is made.
+func (i *Ingester) observeLag(tp kafka.TopicPartition) {
+	if tp.Topic == nil {
+		return
+	}
+	_, high, err := i.consumer.GetWatermarkOffsets(*tp.Topic, tp.Partition)
+	if err != nil || high < 0 {
+		return
+	}
+	lag := high - int64(tp.Offset) - 1
+	if lag < 0 {
+		lag = 0
+	}
+	metrics.SetConsumerLag(*tp.Topic, strconv.Itoa(int(tp.Partition)), lag)
 }
@@ -4,26 +4,44 @@ import (
 	"context"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/mkvy/movies-app/internal/metrics"
+	"github.com/mkvy/movies-app/pkg/tracing"
 	"github.com/mkvy/movies-app/rating/internal/repository"
 	"github.com/mkvy/movies-app/rating/pkg/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
+var tracer = otel.Tracer("github.com/mkvy/movies-app/rating/internal/repository/mysql")
+
 // Repository defines a MySQL-based rating repository.
 type Repository struct {
 	db *sql.DB
 }
 
 // New creates a new MySQL-based rating repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movieexample")
+// The dsn is in the go-sql-driver format, e.g. root:password@/movieexample.
+func New(dsn string) (*Repository, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
 	return &Repository{db}, nil
 }
 
+// Ping checks the database connection, e.g. for readiness checks.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
 // Get retrieves all ratings for a given record.
-func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (_ []model.Rating, err error) {
+	defer metrics.ObserveRepository("mysql", "get", time.Now(), &err, repository.ErrNotFound)
+	ctx, span := startSpan(ctx, "SELECT", recordID, recordType)
+	defer tracing.End(span, &err, repository.ErrNotFound)
 	rows, err := r.db.QueryContext(ctx, "SELECT user_id, value FROM ratings WHERE record_id = ? AND record_type = ?", recordID, recordType)
 	if err != nil {
 		return nil, err
@@ -41,6 +59,7 @@ func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordTyp
 			Value:  model.RatingValue(value),
 		})
 	}
+	span.SetAttributes(attribute.Int("db.rows", len(res)))
 	if len(res) == 0 {
 		return nil, repository.ErrNotFound
 	}
@@ -48,8 +67,25 @@ func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordTyp
 }
 
 // Put adds a rating for a given record.
-func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO ratings (record_id, record_type, user_id, value) VALUES (?, ?, ?, ?)",
+func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (err error) {
+	defer metrics.ObserveRepository("mysql", "put", time.Now(), &err, nil)
+	ctx, span := startSpan(ctx, "INSERT", recordID, recordType)
+	span.SetAttributes(attribute.String("rating.user_id", string(rating.UserID)))
+	defer tracing.End(span, &err)
+	_, err = r.db.ExecContext(ctx, "INSERT INTO ratings (record_id, record_type, user_id, value) VALUES (?, ?, ?, ?)",
 		recordID, recordType, rating.UserID, rating.Value)
 	return err
 }
+
+func startSpan(ctx context.Context, operation string, recordID model.RecordID, recordType model.RecordType) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation+" ratings",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String("ratings"),
+			attribute.String("rating.record_id", string(recordID)),
+			attribute.String("rating.record_type", string(recordType)),
+		),
+	)
+}
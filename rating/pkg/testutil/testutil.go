@@ -10,6 +10,6 @@ import (
 // NewTestRatingGRPCServer creates a new rating gRPC server to be used in tests.
 func NewTestRatingGRPCServer() gen.RatingServiceServer {
 	r := memory.New()
-	ctrl := rating.New(r, nil)
+	ctrl := rating.New(r, nil, nil)
 	return grpchandler.New(ctrl)
 }
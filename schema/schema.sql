@@ -1,2 +1,3 @@
-CREATE TABLE IF NOT EXISTS movies (id VARCHAR(255), title VARCHAR(255), description TEXT, director VARCHAR(255));
-CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT);
\ No newline at end of file
+CREATE TABLE IF NOT EXISTS movies (id VARCHAR(255) PRIMARY KEY, title VARCHAR(255), description TEXT, director VARCHAR(255));
+CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT);
+CREATE TABLE IF NOT EXISTS audit_log (id CHAR(32) PRIMARY KEY, time DATETIME(6) NOT NULL, request_id VARCHAR(255), principal VARCHAR(255) NOT NULL, roles JSON, action VARCHAR(255) NOT NULL, target VARCHAR(255) NOT NULL, before_snapshot JSON, after_snapshot JSON, INDEX (time), INDEX (principal), INDEX (target));
\ No newline at end of file
@@ -5,15 +5,19 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mkvy/movies-app/gen"
+	"github.com/mkvy/movies-app/internal/auth"
 	metadatatest "github.com/mkvy/movies-app/metadata/pkg/testutil"
 	movietest "github.com/mkvy/movies-app/movie/pkg/testutil"
 	"github.com/mkvy/movies-app/pkg/discovery"
 	"github.com/mkvy/movies-app/pkg/discovery/memory"
+	"github.com/mkvy/movies-app/pkg/logging"
 	ratingtest "github.com/mkvy/movies-app/rating/pkg/testutil"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"log"
+	"google.golang.org/grpc/metadata"
 	"net"
+	"time"
 )
 
 const (
@@ -24,19 +28,26 @@ const (
 	metadataServiceAddr = "localhost:8081"
 	ratingServiceAddr   = "localhost:8082"
 	movieServiceAddr    = "localhost:8083"
+
+	testAPIKey = "test-api-key"
 )
 
 func main() {
-	log.Println("Starting the integration test")
+	logger, _, err := logging.New(logging.Config{Level: "info", Format: logging.FormatConsole}, "integration")
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+	logger.Info("Starting the integration test")
 	ctx := context.Background()
 	registry := memory.NewRegistry()
-	log.Println("Setting up service handlers and clients")
+	logger.Info("Setting up service handlers and clients")
 
-	metadataSrv := startMetadataService(ctx, registry)
+	metadataSrv := startMetadataService(ctx, registry, logger)
 	defer metadataSrv.GracefulStop()
-	ratingSrv := startRatingService(ctx, registry)
+	ratingSrv := startRatingService(ctx, registry, logger)
 	defer ratingSrv.GracefulStop()
-	movieSrv := startMovieService(ctx, registry)
+	movieSrv := startMovieService(ctx, registry, logger)
 	defer movieSrv.GracefulStop()
 
 	opts := grpc.WithTransportCredentials(insecure.NewCredentials())
@@ -54,6 +65,7 @@ func main() {
 	}
 	defer ratingConn.Close()
 	ratingClient := gen.NewRatingServiceClient(ratingConn)
+	userCtx := metadata.AppendToOutgoingContext(ctx, auth.APIKeyHeader, testAPIKey)
 
 	movieConn, err := grpc.Dial(movieServiceAddr, opts)
 	if err != nil {
@@ -62,7 +74,7 @@ func main() {
 	defer movieConn.Close()
 	movieClient := gen.NewMovieServiceClient(movieConn)
 
-	log.Println("Saving test metadata via metadata service")
+	logger.Info("Saving test metadata via metadata service")
 	m := &gen.Metadata{
 		Id:          "the-movie",
 		Title:       "The Movie",
@@ -71,46 +83,67 @@ func main() {
 	}
 
 	if _, err := metadataClient.PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: m}); err != nil {
-		log.Fatalf("put metadata: %v", err)
+		logger.Fatal("Failed to put metadata", zap.Error(err))
 	}
 
-	log.Println("Retrieving test metadata via metadata service")
+	logger.Info("Retrieving test metadata via metadata service")
 
 	getMetadataResp, err := metadataClient.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: m.Id})
 	if err != nil {
-		log.Fatalf("get metadata: %v", err)
+		logger.Fatal("Failed to get metadata", zap.Error(err))
 	}
 	//cmp coz ignoreunexport fields
 	if diff := cmp.Diff(getMetadataResp.Metadata, m, cmpopts.IgnoreUnexported(gen.Metadata{})); diff != "" {
-		log.Fatalf("get metadata after put mismatch: %v", diff)
+		logger.Fatal("Get metadata after put mismatch", zap.String("diff", diff))
+	}
+
+	logger.Info("Searching test metadata via metadata service")
+	searchResp, err := metadataClient.SearchMetadata(ctx, &gen.SearchMetadataRequest{Query: "mr. d"})
+	if err != nil {
+		logger.Fatal("Failed to search metadata", zap.Error(err))
+	}
+	if len(searchResp.Metadata) != 1 || searchResp.Metadata[0].Id != m.Id {
+		logger.Fatal("Search metadata mismatch", zap.Int("results", len(searchResp.Metadata)))
 	}
 
-	log.Println("Saving first rating via rating service")
+	logger.Info("Saving first rating via rating service")
 	const userID = "user0"
 	const recordTypeMovie = "movie"
 	firstRating := int32(5)
-	if _, err = ratingClient.PutRating(ctx, &gen.PutRatingRequest{
+	if _, err = ratingClient.PutRating(userCtx, &gen.PutRatingRequest{
 		UserId:      userID,
 		RecordId:    m.Id,
 		RecordType:  recordTypeMovie,
 		RatingValue: firstRating,
 	}); err != nil {
-		log.Fatalf("put rating: %v", err)
+		logger.Fatal("Failed to put rating", zap.Error(err))
 	}
-	log.Println("Retrieving initial aggregated rating via rating service")
+	logger.Info("Retrieving initial aggregated rating via rating service")
 	getAggregatedRatingResp, err := ratingClient.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{
 		RecordId:   m.Id,
 		RecordType: recordTypeMovie,
 	})
 	if err != nil {
-		log.Fatalf("get aggreggated rating: %v", err)
+		logger.Fatal("Failed to get aggregated rating", zap.Error(err))
 	}
 
 	if got, want := getAggregatedRatingResp.RatingValue, float64(5); got != want {
-		log.Fatalf("rating mismatch: got %v want %v", got, want)
+		logger.Fatal("Rating mismatch", zap.Float64("got", got), zap.Float64("want", want))
+	}
+
+	logger.Info("Retrieving aggregated ratings in a batch via rating service")
+	batchResp, err := ratingClient.BatchGetAggregatedRatings(ctx, &gen.BatchGetAggregatedRatingsRequest{
+		RecordIds:  []string{m.Id, "unrated-movie"},
+		RecordType: recordTypeMovie,
+	})
+	if err != nil {
+		logger.Fatal("Failed to get aggregated ratings", zap.Error(err))
+	}
+	if diff := cmp.Diff(map[string]float64{m.Id: 5}, batchResp.RatingValues); diff != "" {
+		logger.Fatal("Batch rating mismatch", zap.String("diff", diff))
 	}
 
-	log.Println("Getting movie details via movie service")
+	logger.Info("Getting movie details via movie service")
 
 	wantMovieDetails := &gen.MovieDetails{
 		Rating:   float64(firstRating),
@@ -119,57 +152,81 @@ func main() {
 
 	getMovieDetailsResp, err := movieClient.GetMovieDetails(ctx, &gen.GetMovieDetailsRequest{MovieId: m.Id})
 	if err != nil {
-		log.Fatalf("get movie details: %v", err)
+		logger.Fatal("Failed to get movie details", zap.Error(err))
 	}
 	if diff := cmp.Diff(getMovieDetailsResp.MovieDetails, wantMovieDetails, cmpopts.IgnoreUnexported(gen.MovieDetails{}, gen.Metadata{})); diff != "" {
-		log.Fatalf("get movie details after put mismatch: %v", err)
+		logger.Fatal("Get movie details after put mismatch", zap.String("diff", diff))
 	}
 
-	log.Println("Saving second rating via rating service")
+	logger.Info("Watching movie details via movie service")
+	watchCtx, cancelWatch := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelWatch()
+	watchStream, err := movieClient.WatchMovieDetails(watchCtx, &gen.WatchMovieDetailsRequest{MovieId: m.Id})
+	if err != nil {
+		logger.Fatal("Failed to watch movie details", zap.Error(err))
+	}
+	watchResp, err := watchStream.Recv()
+	if err != nil {
+		logger.Fatal("Failed to receive movie details", zap.Error(err))
+	}
+	if diff := cmp.Diff(watchResp.MovieDetails, wantMovieDetails, cmpopts.IgnoreUnexported(gen.MovieDetails{}, gen.Metadata{})); diff != "" {
+		logger.Fatal("Watched movie details mismatch", zap.String("diff", diff))
+	}
+
+	logger.Info("Saving second rating via rating service")
 
 	secondRating := int32(1)
-	if _, err = ratingClient.PutRating(ctx, &gen.PutRatingRequest{
+	if _, err = ratingClient.PutRating(userCtx, &gen.PutRatingRequest{
 		UserId:      userID,
 		RecordId:    m.Id,
 		RecordType:  recordTypeMovie,
 		RatingValue: secondRating,
 	}); err != nil {
-		log.Fatalf("put rating: %v", err)
+		logger.Fatal("Failed to put rating", zap.Error(err))
 	}
 
-	log.Println("Getting new aggregated rating via rating service")
+	logger.Info("Getting new aggregated rating via rating service")
 	getAggregatedRatingResp, err = ratingClient.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{
 		RecordId:   m.Id,
 		RecordType: recordTypeMovie,
 	})
 	if err != nil {
-		log.Fatalf("get aggreggated rating: %v", err)
+		logger.Fatal("Failed to get aggregated rating", zap.Error(err))
 	}
 
 	wantRating := float64((firstRating + secondRating) / 2)
 	if got, want := getAggregatedRatingResp.RatingValue, wantRating; got != want {
-		log.Fatalf("rating mismatch: got %v want %v", got, want)
+		logger.Fatal("Rating mismatch", zap.Float64("got", got), zap.Float64("want", want))
 	}
-	log.Println("Getting updated movie details via movie service")
+	logger.Info("Getting updated movie details via movie service")
 
 	getMovieDetailsResp, err = movieClient.GetMovieDetails(ctx, &gen.GetMovieDetailsRequest{MovieId: m.Id})
 	if err != nil {
-		log.Fatalf("get movie details: %v", err)
+		logger.Fatal("Failed to get movie details", zap.Error(err))
 	}
 	wantMovieDetails.Rating = wantRating
 	if diff := cmp.Diff(getMovieDetailsResp.MovieDetails, wantMovieDetails, cmpopts.IgnoreUnexported(gen.MovieDetails{}, gen.Metadata{})); diff != "" {
-		log.Fatalf("get movie details after update mismatch: %v", err)
+		logger.Fatal("Get movie details after update mismatch", zap.String("diff", diff))
+	}
+
+	logger.Info("Receiving updated movie details from the watch stream")
+	watchResp, err = watchStream.Recv()
+	if err != nil {
+		logger.Fatal("Failed to receive updated movie details", zap.Error(err))
+	}
+	if diff := cmp.Diff(watchResp.MovieDetails, wantMovieDetails, cmpopts.IgnoreUnexported(gen.MovieDetails{}, gen.Metadata{})); diff != "" {
+		logger.Fatal("Watched movie details after update mismatch", zap.String("diff", diff))
 	}
 
-	log.Println("Integration test execution successful")
+	logger.Info("Integration test execution successful")
 }
 
-func startMetadataService(ctx context.Context, registry discovery.Registry) *grpc.Server {
-	log.Println("Starting metadata service on " + metadataServiceAddr)
+func startMetadataService(ctx context.Context, registry discovery.Registry, logger *zap.Logger) *grpc.Server {
+	logger.Info("Starting metadata service", zap.String("addr", metadataServiceAddr))
 	h := metadatatest.NewTestMetadataGRPCServer()
 	l, err := net.Listen("tcp", metadataServiceAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Fatal("Failed to listen", zap.Error(err))
 	}
 	srv := grpc.NewServer()
 	gen.RegisterMetadataServiceServer(srv, h)
@@ -185,14 +242,22 @@ func startMetadataService(ctx context.Context, registry discovery.Registry) *grp
 	return srv
 }
 
-func startRatingService(ctx context.Context, registry discovery.Registry) *grpc.Server {
-	log.Println("Starting rating service on " + ratingServiceAddr)
+func startRatingService(ctx context.Context, registry discovery.Registry, logger *zap.Logger) *grpc.Server {
+	logger.Info("Starting rating service", zap.String("addr", ratingServiceAddr))
 	h := ratingtest.NewTestRatingGRPCServer()
 	l, err := net.Listen("tcp", ratingServiceAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Fatal("Failed to listen", zap.Error(err))
 	}
-	srv := grpc.NewServer()
+	authenticator, err := auth.New(auth.Config{
+		Enabled: true,
+		APIKeys: []auth.APIKey{{Key: testAPIKey, Subject: "user0"}},
+		Public:  []string{gen.RatingService_GetAggregatedRating_FullMethodName, gen.RatingService_BatchGetAggregatedRatings_FullMethodName},
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize authentication", zap.Error(err))
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()))
 	gen.RegisterRatingServiceServer(srv, h)
 	go func() {
 		if err := srv.Serve(l); err != nil {
@@ -206,12 +271,12 @@ func startRatingService(ctx context.Context, registry discovery.Registry) *grpc.
 	return srv
 }
 
-func startMovieService(ctx context.Context, registry discovery.Registry) *grpc.Server {
-	log.Println("Starting movie service on " + movieServiceAddr)
+func startMovieService(ctx context.Context, registry discovery.Registry, logger *zap.Logger) *grpc.Server {
+	logger.Info("Starting movie service", zap.String("addr", movieServiceAddr))
 	h := movietest.NewTestMovieGRPCServer(registry)
 	l, err := net.Listen("tcp", movieServiceAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Fatal("Failed to listen", zap.Error(err))
 	}
 	srv := grpc.NewServer()
 	gen.RegisterMovieServiceServer(srv, h)